@@ -4,4 +4,8 @@ go 1.23.10
 
 require github.com/spf13/pflag v1.0.6
 
+require golang.org/x/sys v0.30.0
+
+require golang.org/x/text v0.22.0
+
 replace gocore/utils => ./utils
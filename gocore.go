@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"gocore/utils"
 	"os"
+	"strconv"
+	"strings"
 
 	flag "github.com/spf13/pflag"
 )
@@ -45,21 +47,38 @@ func main() {
 
 	case "mkdir":
 		mkdirCmd := flag.NewFlagSet("mkdir", flag.ExitOnError)
-		permFlag := mkdirCmd.IntP("mode", "m", 0755, "set file mode")
+		modeFlag := mkdirCmd.StringP("mode", "m", "", "set file mode (as in chmod), not a=rwx - umask")
 		parentsFlag := mkdirCmd.BoolP("parents", "p", false, "Create any missing intermediate pathname components.")
+		verboseFlag := mkdirCmd.BoolP("verbose", "v", false, "print a message for each created directory")
+		contextFlag := mkdirCmd.StringP("context", "Z", "", "set the SELinux security context of each created directory to CTX; CTX is required")
+		mkdirCmd.Lookup("context").NoOptDefVal = "default"
 		mkdirCmd.Parse(os.Args[2:])
-		err := utils.Mkdir(*permFlag, *parentsFlag, mkdirCmd.Args())
+		err := utils.Mkdir(*modeFlag, *parentsFlag, *verboseFlag, *contextFlag, mkdirCmd.Args())
 		if err != nil {
 			fmt.Println(err)
 		}
 
 	case "rm":
 		rmCmd := flag.NewFlagSet("rm", flag.ExitOnError)
-		interactiveFlag := rmCmd.BoolP("interactive", "i", false, "prompt before every removal")
+		interactiveFlag := rmCmd.StringP("interactive", "i", "", "prompt according to WHEN: never, once (-I), or always (-i); without WHEN, prompt before every removal")
+		rmCmd.Lookup("interactive").NoOptDefVal = "always"
+		promptOnceFlag := rmCmd.BoolP("prompt-once", "I", false, "prompt once before removing more than three files, or when removing recursively")
 		forceFlag := rmCmd.BoolP("force", "f", false, "Do not prompt for confirmation. Do not write diagnostic messages or modify the exit status in the case of no file operands, or in the case of operands that do not exist.")
 		recursiveFlag := rmCmd.BoolP("recursive", "r", false, "Remove file hierarchies.")
+		trashFlag := rmCmd.Bool("trash", false, "move files to the trash instead of removing them")
 		rmCmd.Parse(os.Args[2:])
-		err := utils.Rm(*interactiveFlag, *forceFlag, *recursiveFlag, rmCmd.Args())
+		if *promptOnceFlag {
+			*interactiveFlag = "once"
+		}
+		err := utils.Rm(*interactiveFlag, *forceFlag, *recursiveFlag, *trashFlag, rmCmd.Args())
+		if err != nil {
+			fmt.Println(err)
+		}
+
+	case "trash":
+		trashCmd := flag.NewFlagSet("trash", flag.ExitOnError)
+		trashCmd.Parse(os.Args[2:])
+		err := utils.Trash(trashCmd.Args())
 		if err != nil {
 			fmt.Println(err)
 		}
@@ -67,8 +86,13 @@ func main() {
 	case "cat":
 		catCmd := flag.NewFlagSet("cat", flag.ExitOnError)
 		bytesFlag := catCmd.BoolP("bytes", "u", false, "Write bytes from the input file to the standard output without delay as each is read.")
+		progressFlag := catCmd.Bool("progress", false, "show transfer progress on stderr when it is a terminal")
 		catCmd.Parse(os.Args[2:])
-		err := utils.Cat(*bytesFlag, catCmd.Args()...)
+		var progress func(utils.Progress)
+		if *progressFlag {
+			progress = utils.ProgressBar()
+		}
+		err := utils.Cat(*bytesFlag, progress, catCmd.Args()...)
 		if err != nil {
 			fmt.Println(err)
 		}
@@ -98,13 +122,42 @@ func main() {
 		cpCmd := flag.NewFlagSet("cp", flag.ExitOnError)
 		followSymbolicFlag := cpCmd.BoolP("follow-symbolic", "H", false, "follow command-line symbolic links in SOURCE")
 		recursiveFlag := cpCmd.BoolP("recursive", "r", false, "copy directories recursively")
+		recursiveUpperFlag := cpCmd.BoolP("recursive-posix", "R", false, "copy directories recursively")
+		cpCmd.MarkHidden("recursive-posix")
 		dereferenceFlag := cpCmd.BoolP("dereference", "L", false, "always follow symbolic links in SOURCE")
 		noDereferenceFlag := cpCmd.BoolP("no-dereference", "P", false, "never follow symbolic links in SOURCE")
-		preserveFlag := cpCmd.BoolP("preserve", "p", false, "preserve the file attributes")
+		preserveFlag := cpCmd.StringP("preserve", "p", "", "preserve the specified attributes: mode, ownership, timestamps, links, xattr, context, all")
+		cpCmd.Lookup("preserve").NoOptDefVal = "mode,ownership,timestamps"
+		noPreserveFlag := cpCmd.String("no-preserve", "", "don't preserve the specified attributes")
+		interactiveFlag := cpCmd.BoolP("interactive", "i", false, "prompt before overwrite (overrides a previous -n option)")
+		forceFlag := cpCmd.BoolP("force", "f", false, "if an existing destination file cannot be opened, remove it and try again")
+		noClobberFlag := cpCmd.BoolP("no-clobber", "n", false, "do not overwrite an existing file (overrides a previous -i option)")
+		updateFlag := cpCmd.BoolP("update", "u", false, "copy only when the SOURCE file is newer than the destination file or when the destination file is missing")
+		verboseFlag := cpCmd.BoolP("verbose", "v", false, "explain what is being done")
+		archiveFlag := cpCmd.BoolP("archive", "a", false, "same as -dR --preserve=all")
+		noTargetDirFlag := cpCmd.BoolP("no-target-directory", "T", false, "treat DEST as a normal file")
+		targetDirFlag := cpCmd.StringP("target-directory", "t", "", "copy all SOURCE arguments into DIRECTORY")
+		backupFlag := cpCmd.String("backup", "", "make a backup of each existing destination file; CONTROL is none, numbered, existing or simple")
+		cpCmd.Lookup("backup").NoOptDefVal = "default"
+		backupDefaultFlag := cpCmd.BoolP("backup-default", "b", false, "like --backup but does not accept an argument")
+		cpCmd.MarkHidden("backup-default")
+		suffixFlag := cpCmd.StringP("suffix", "S", "", "override the usual backup suffix")
+		sparseFlag := cpCmd.String("sparse", "auto", "control creation of sparse files: auto, always or never")
+		reflinkFlag := cpCmd.String("reflink", "auto", "control clone/CoW copies: auto, always or never")
+		cpCmd.Lookup("reflink").NoOptDefVal = "always"
+		progressFlag := cpCmd.Bool("progress", false, "show transfer progress on stderr when it is a terminal")
 		cpCmd.Parse(os.Args[2:])
-		err := utils.Cp(cpCmd.Args(), *followSymbolicFlag, *recursiveFlag, *dereferenceFlag, *noDereferenceFlag, *preserveFlag)
+		var progress func(utils.Progress)
+		if *progressFlag {
+			progress = utils.ProgressBar()
+		}
+		if *backupDefaultFlag && *backupFlag == "" {
+			*backupFlag = "default"
+		}
+		err := utils.Cp(cpCmd.Args(), *followSymbolicFlag, *recursiveFlag || *recursiveUpperFlag, *dereferenceFlag, *noDereferenceFlag, *preserveFlag, *noPreserveFlag, *interactiveFlag, *forceFlag, *noClobberFlag, *updateFlag, *verboseFlag, *archiveFlag, *noTargetDirFlag, *targetDirFlag, *backupFlag, *suffixFlag, *sparseFlag, *reflinkFlag, progress)
 		if err != nil {
-			fmt.Println(err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
 
 	case "cal":
@@ -116,18 +169,51 @@ func main() {
 		cmpCmd := flag.NewFlagSet("cmp", flag.ExitOnError)
 		verboseFlag := cmpCmd.BoolP("verbose", "l", false, "output byte numbers and differing byte values")
 		quietFlag := cmpCmd.BoolP("quiet", "s", false, "suppress all normal output")
+		printBytesFlag := cmpCmd.BoolP("print-bytes", "b", false, "print differing bytes")
+		limitFlag := cmpCmd.StringP("bytes", "n", "", "compare at most LIMIT bytes")
+		skipFlag := cmpCmd.StringP("ignore-initial", "i", "", "skip first SKIP bytes of both inputs, or SKIP1 and SKIP2 bytes with SKIP1:SKIP2")
 		cmpCmd.Parse(os.Args[2:])
-		_, _, err := utils.Cmp(cmpCmd.Arg(0), cmpCmd.Arg(1), *verboseFlag, *quietFlag)
+
+		// Optional SKIP1 and SKIP2 operands, as in cmp file1 file2 SKIP1 SKIP2
+		skip := *skipFlag
+		if cmpCmd.NArg() > 2 && skip == "" {
+			skip = cmpCmd.Arg(2)
+			if cmpCmd.NArg() > 3 {
+				skip += ":" + cmpCmd.Arg(3)
+			}
+		}
+
+		_, status, err := utils.Cmp(cmpCmd.Arg(0), cmpCmd.Arg(1), *verboseFlag, *quietFlag, *printBytesFlag, *limitFlag, skip)
 		if err != nil {
-			fmt.Println(err)
+			fmt.Fprintln(os.Stderr, err)
 		}
+		os.Exit(status)
 
 	case "mv":
 		mvCmd := flag.NewFlagSet("mv", flag.ExitOnError)
 		interactiveFlag := mvCmd.BoolP("interactive", "i", false, "prompt before overwrite")
 		forceFlag := mvCmd.BoolP("force", "f", false, "do not prompt before overwriting")
+		noClobberFlag := mvCmd.BoolP("no-clobber", "n", false, "do not overwrite an existing file")
+		updateFlag := mvCmd.BoolP("update", "u", false, "move only when the SOURCE file is newer than the destination file or when the destination file is missing")
+		verboseFlag := mvCmd.BoolP("verbose", "v", false, "explain what is being done")
+		noTargetDirFlag := mvCmd.BoolP("no-target-directory", "T", false, "treat DEST as a normal file")
+		targetDirFlag := mvCmd.StringP("target-directory", "t", "", "move all SOURCE arguments into DIRECTORY")
+		backupFlag := mvCmd.String("backup", "", "make a backup of each existing destination file; CONTROL is none, numbered, existing or simple")
+		mvCmd.Lookup("backup").NoOptDefVal = "default"
+		backupDefaultFlag := mvCmd.BoolP("backup-default", "b", false, "like --backup but does not accept an argument")
+		mvCmd.MarkHidden("backup-default")
+		suffixFlag := mvCmd.StringP("suffix", "S", "", "override the usual backup suffix")
+		exchangeFlag := mvCmd.Bool("exchange", false, "exchange SOURCE and DEST atomically")
+		progressFlag := mvCmd.Bool("progress", false, "show transfer progress on stderr when it is a terminal")
 		mvCmd.Parse(os.Args[2:])
-		err := utils.Mv(mvCmd.Args(), *interactiveFlag, *forceFlag)
+		var progress func(utils.Progress)
+		if *progressFlag {
+			progress = utils.ProgressBar()
+		}
+		if *backupDefaultFlag && *backupFlag == "" {
+			*backupFlag = "default"
+		}
+		err := utils.Mv(mvCmd.Args(), *interactiveFlag, *forceFlag, *noClobberFlag, *updateFlag, *verboseFlag, *noTargetDirFlag, *targetDirFlag, *backupFlag, *suffixFlag, *exchangeFlag, progress)
 
 		if err != nil {
 			fmt.Println(err)
@@ -137,11 +223,20 @@ func main() {
 		teeCmd := flag.NewFlagSet("tee", flag.ExitOnError)
 		appendFlag := teeCmd.BoolP("append", "a", false, "append to the given FILEs, do not overwrite")
 		ignoreInterruptsFlag := teeCmd.BoolP("ignore-interrupts", "i", false, "ignore interrupt signals")
+		outputErrorFlag := teeCmd.String("output-error", "", "set behavior on write error; MODE is warn, warn-nopipe, exit or exit-nopipe")
+		teeCmd.Lookup("output-error").NoOptDefVal = "warn-nopipe"
+		outputErrorDefaultFlag := teeCmd.BoolP("output-error-default", "p", false, "operate in a more appropriate MODE with pipes")
+		teeCmd.MarkHidden("output-error-default")
 		teeCmd.Parse(os.Args[2:])
-		err := utils.Tee(os.Stdin, teeCmd.Args(), *appendFlag, *ignoreInterruptsFlag)
+		if *outputErrorDefaultFlag && *outputErrorFlag == "" {
+			*outputErrorFlag = "warn-nopipe"
+		}
+		err := utils.Tee(os.Stdin, teeCmd.Args(), *appendFlag, *ignoreInterruptsFlag, *outputErrorFlag)
 
+		// Standard output carries the data, so errors go to stderr
 		if err != nil {
-			fmt.Println(err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
 
 	case "ln":
@@ -150,8 +245,22 @@ func main() {
 		forceFlag := lnCmd.BoolP("force", "f", false, "remove existing destination files")
 		logicalFlag := lnCmd.BoolP("logical", "L", false, "dereference TARGETs that are symbolic links")
 		physicalFlag := lnCmd.BoolP("physical", "P", false, "make hard links directly to symbolic links")
+		relativeFlag := lnCmd.BoolP("relative", "r", false, "with -s, create links relative to link location")
+		noDereferenceFlag := lnCmd.BoolP("no-dereference", "n", false, "treat LINK_NAME as a normal file if it is a symbolic link to a directory")
+		interactiveFlag := lnCmd.BoolP("interactive", "i", false, "prompt whether to remove destinations")
+		verboseFlag := lnCmd.BoolP("verbose", "v", false, "print name of each linked file")
+		noTargetDirFlag := lnCmd.BoolP("no-target-directory", "T", false, "treat LINK_NAME as a normal file always")
+		targetDirFlag := lnCmd.StringP("target-directory", "t", "", "specify the DIRECTORY in which to create the links")
+		backupFlag := lnCmd.String("backup", "", "make a backup of each existing destination file; CONTROL is none, numbered, existing or simple")
+		lnCmd.Lookup("backup").NoOptDefVal = "default"
+		backupDefaultFlag := lnCmd.BoolP("backup-default", "b", false, "like --backup but does not accept an argument")
+		lnCmd.MarkHidden("backup-default")
+		suffixFlag := lnCmd.StringP("suffix", "S", "", "override the usual backup suffix")
 		lnCmd.Parse(os.Args[2:])
-		err := utils.Ln(lnCmd.Args(), *symlinkFlag, *forceFlag, *logicalFlag, *physicalFlag)
+		if *backupDefaultFlag && *backupFlag == "" {
+			*backupFlag = "default"
+		}
+		err := utils.Ln(lnCmd.Args(), *symlinkFlag, *forceFlag, *logicalFlag, *physicalFlag, *relativeFlag, *noDereferenceFlag, *interactiveFlag, *verboseFlag, *noTargetDirFlag, *targetDirFlag, *backupFlag, *suffixFlag)
 
 		if err != nil {
 			fmt.Println(err)
@@ -161,22 +270,92 @@ func main() {
 		com1Flag := commCmd.BoolP("1", "1", false, "suppress column 1 (lines unique to FILE1)")
 		com2Flag := commCmd.BoolP("2", "2", false, "suppress column 2 (lines unique to FILE2)")
 		com3Flag := commCmd.BoolP("3", "3", false, "suppress column 3 (lines that appear in both files)")
+		checkOrderFlag := commCmd.Bool("check-order", false, "check that the input is correctly sorted, even if all input lines are pairable")
+		noCheckOrderFlag := commCmd.Bool("nocheck-order", false, "do not check that the input is correctly sorted")
+		delimiterFlag := commCmd.String("output-delimiter", "\t", "separate columns with STR")
+		zeroTerminatedFlag := commCmd.BoolP("zero-terminated", "z", false, "line delimiter is NUL, not newline")
+		totalFlag := commCmd.Bool("total", false, "output a summary")
 		commCmd.Parse(os.Args[2:])
-		err := utils.Comm(commCmd.Arg(0), commCmd.Arg(1), *com1Flag, *com2Flag, *com3Flag)
+		err := utils.Comm(commCmd.Arg(0), commCmd.Arg(1), *com1Flag, *com2Flag, *com3Flag, *checkOrderFlag, *noCheckOrderFlag, *delimiterFlag, *zeroTerminatedFlag, *totalFlag)
 
+		// Standard output carries the columns, so errors go to stderr
 		if err != nil {
-			fmt.Println(err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
 
 	case "chown":
 		chownCmd := flag.NewFlagSet("chown", flag.ExitOnError)
-		noDereferenceFlag := chownCmd.BoolP("no-dereference", "d", false, "affect symbolic links instead of any referenced file (useful only on systems that can change the ownership of a symlink)")
+		noDereferenceFlag := chownCmd.BoolP("no-dereference", "h", false, "affect symbolic links instead of any referenced file (useful only on systems that can change the ownership of a symlink)")
+		noDereferenceOldFlag := chownCmd.BoolP("no-dereference-compat", "d", false, "same as -h")
+		chownCmd.MarkHidden("no-dereference-compat")
 		recursiveFlag := chownCmd.BoolP("reccursive", "R", false, "operate on files and directories recursively")
-		physicalFlag := chownCmd.BoolP("physical", "P", false, "do not traverse any symbolic links")
-		logicalFlag := chownCmd.BoolP("logical", "L", false, "traverse every symbolic link to a directory encountered")
-		hybridFlag := chownCmd.BoolP("Hybrid", "H", false, "if a command line argument is a symbolic link to a directory, traverse it")
+		traversal := traversalFlags(chownCmd)
+		fromFlag := chownCmd.String("from", "", "change the ownership of each file only if its current owner and/or group match those specified here")
+		referenceFlag := chownCmd.String("reference", "", "use RFILE's owner and group rather than specifying OWNER:GROUP values")
+		verboseFlag := chownCmd.BoolP("verbose", "v", false, "output a diagnostic for every file processed")
+		changesFlag := chownCmd.BoolP("changes", "c", false, "like verbose but report only when a change is made")
+		preserveRootFlag := chownCmd.Bool("preserve-root", false, "fail to operate recursively on '/'")
+		noPreserveRootFlag := chownCmd.Bool("no-preserve-root", false, "do not treat '/' specially (the default)")
 		chownCmd.Parse(os.Args[2:])
-		err := utils.Chown(chownCmd.Arg(0), chownCmd.Args()[1:], *noDereferenceFlag, *recursiveFlag, *physicalFlag, *logicalFlag, *hybridFlag)
+
+		owner, files := "", chownCmd.Args()
+		if *referenceFlag == "" && len(files) > 0 {
+			owner, files = files[0], files[1:]
+		}
+		err := utils.Chown(owner, files, *noDereferenceFlag || *noDereferenceOldFlag, *recursiveFlag, *traversal, *fromFlag, *referenceFlag, *verboseFlag, *changesFlag, *preserveRootFlag && !*noPreserveRootFlag)
+
+		if err != nil {
+			fmt.Println(err)
+		}
+	case "chgrp":
+		chgrpCmd := flag.NewFlagSet("chgrp", flag.ExitOnError)
+		noDereferenceFlag := chgrpCmd.BoolP("no-dereference", "h", false, "affect symbolic links instead of any referenced file (useful only on systems that can change the ownership of a symlink)")
+		recursiveFlag := chgrpCmd.BoolP("recursive", "R", false, "operate on files and directories recursively")
+		traversal := traversalFlags(chgrpCmd)
+		referenceFlag := chgrpCmd.String("reference", "", "use RFILE's group rather than specifying a GROUP value")
+		verboseFlag := chgrpCmd.BoolP("verbose", "v", false, "output a diagnostic for every file processed")
+		changesFlag := chgrpCmd.BoolP("changes", "c", false, "like verbose but report only when a change is made")
+		preserveRootFlag := chgrpCmd.Bool("preserve-root", false, "fail to operate recursively on '/'")
+		noPreserveRootFlag := chgrpCmd.Bool("no-preserve-root", false, "do not treat '/' specially (the default)")
+		chgrpCmd.Parse(os.Args[2:])
+
+		group, files := "", chgrpCmd.Args()
+		if *referenceFlag == "" && len(files) > 0 {
+			group, files = files[0], files[1:]
+		}
+		err := utils.Chgrp(group, files, *noDereferenceFlag, *recursiveFlag, *traversal, *referenceFlag, *verboseFlag, *changesFlag, *preserveRootFlag && !*noPreserveRootFlag)
+
+		if err != nil {
+			fmt.Println(err)
+		}
+	case "chmod":
+		chmodCmd := flag.NewFlagSet("chmod", flag.ExitOnError)
+		recursiveFlag := chmodCmd.BoolP("recursive", "R", false, "change files and directories recursively")
+		referenceFlag := chmodCmd.String("reference", "", "use RFILE's mode instead of specifying MODE values")
+		verboseFlag := chmodCmd.BoolP("verbose", "v", false, "output a diagnostic for every file processed")
+		changesFlag := chmodCmd.BoolP("changes", "c", false, "like verbose but report only when a change is made")
+		quietFlag := chmodCmd.BoolP("silent", "f", false, "suppress most error messages")
+		preserveRootFlag := chmodCmd.Bool("preserve-root", false, "fail to operate recursively on '/'")
+		noPreserveRootFlag := chmodCmd.Bool("no-preserve-root", false, "do not treat '/' specially (the default)")
+
+		// Modes such as -w or -rx look like flags, take them out before parsing
+		var mode string
+		var args []string
+		for _, arg := range os.Args[2:] {
+			if mode == "" && len(arg) > 1 && arg[0] == '-' && arg != "--" && strings.Trim(arg, "ugoarwxXst,+-=") == "" {
+				mode = arg
+				continue
+			}
+			args = append(args, arg)
+		}
+		chmodCmd.Parse(args)
+
+		files := chmodCmd.Args()
+		if mode == "" && *referenceFlag == "" && len(files) > 0 {
+			mode, files = files[0], files[1:]
+		}
+		err := utils.Chmod(mode, files, *recursiveFlag, *referenceFlag, *verboseFlag, *changesFlag, *quietFlag, *preserveRootFlag && !*noPreserveRootFlag)
 
 		if err != nil {
 			fmt.Println(err)
@@ -201,22 +380,38 @@ func main() {
 		counterFlag := uniqCmd.BoolP("count", "c", false, "prefix lines by the number of occurrences")
 		repeatedFlag := uniqCmd.BoolP("repeated", "d", false, "only print duplicate lines, one for each group")
 		uniqueFlag := uniqCmd.BoolP("unique", "u", false, "only print unique lines")
-		fieldsFlag := uniqCmd.UintP("skip-fields", "f", 1, "avoid comparing the first N fields")
-		charsFlag := uniqCmd.UintP("skip-chars", "s", 1, "avoid comparing the first N characters")
+		fieldsFlag := uniqCmd.UintP("skip-fields", "f", 0, "avoid comparing the first N fields")
+		charsFlag := uniqCmd.UintP("skip-chars", "s", 0, "avoid comparing the first N characters")
+		ignoreCaseFlag := uniqCmd.BoolP("ignore-case", "i", false, "ignore differences in case when comparing")
+		checkCharsFlag := uniqCmd.UintP("check-chars", "w", 0, "compare no more than N characters in lines")
+		zeroTerminatedFlag := uniqCmd.BoolP("zero-terminated", "z", false, "line delimiter is NUL, not newline")
+		allRepeatedFlag := uniqCmd.StringP("all-repeated", "D", "", "print all duplicate lines, groups can be delimited with an empty line; METHOD is none, prepend or separate")
+		uniqCmd.Lookup("all-repeated").NoOptDefVal = "none"
+		groupFlag := uniqCmd.String("group", "", "show all items, separating groups with an empty line; METHOD is separate, prepend, append or both")
+		uniqCmd.Lookup("group").NoOptDefVal = "separate"
 		uniqCmd.Parse(os.Args[2:])
-		err := utils.Uniq(uniqCmd.Arg(0), uniqCmd.Arg(1), *repeatedFlag, *uniqueFlag, *counterFlag, *fieldsFlag, *charsFlag)
+		width := -1
+		if uniqCmd.Changed("check-chars") {
+			width = int(*checkCharsFlag)
+		}
+		err := utils.Uniq(uniqCmd.Arg(0), uniqCmd.Arg(1), *repeatedFlag, *uniqueFlag, *counterFlag, *fieldsFlag, *charsFlag, *ignoreCaseFlag, width, *zeroTerminatedFlag, *allRepeatedFlag, *groupFlag)
 
 		if err != nil {
 			fmt.Println(err)
 		}
 	case "cut":
 		cutCmd := flag.NewFlagSet("cut", flag.ExitOnError)
+		bytesFlag := cutCmd.StringP("bytes", "b", "", "select only these bytes")
 		charFlag := cutCmd.StringP("characters", "c", "", "select only these characters")
 		fieldFlag := cutCmd.StringP("fields", "f", "", "select only these fields;  also print any line that contains no delimiter character, unless the -s option is specified")
 		delimiterFlag := cutCmd.StringP("delimiter", "d", "", "use DELIM instead of TAB for field delimiter")
 		onlyDelimited := cutCmd.BoolP("only-delimited", "s", false, "do not print lines not containing delimiters")
+		noSplitFlag := cutCmd.BoolP("no-split", "n", false, "with -b, do not split multibyte characters")
+		complementFlag := cutCmd.Bool("complement", false, "complement the set of selected bytes, characters or fields")
+		outputDelimiterFlag := cutCmd.String("output-delimiter", "", "use STRING as the output delimiter, the default is to use the input delimiter")
+		zeroTerminatedFlag := cutCmd.BoolP("zero-terminated", "z", false, "line delimiter is NUL, not newline")
 		cutCmd.Parse(os.Args[2:])
-		err := utils.Cut(cutCmd.Args(), *charFlag, *fieldFlag, *delimiterFlag, *onlyDelimited)
+		err := utils.Cut(cutCmd.Args(), *bytesFlag, *charFlag, *fieldFlag, *delimiterFlag, *outputDelimiterFlag, *onlyDelimited, *noSplitFlag, *complementFlag, *zeroTerminatedFlag)
 
 		if err != nil {
 			fmt.Println(err)
@@ -239,3 +434,39 @@ func main() {
 	}
 
 }
+
+// Flag value of one of -H, -L and -P. They share a single traversal setting,
+// so the last one given wins as POSIX requires (Chown, Chgrp)
+type traversalFlag struct {
+	traversal *string
+	value     string
+}
+
+func (t *traversalFlag) String() string {
+	return strconv.FormatBool(*t.traversal == t.value)
+}
+
+func (t *traversalFlag) Set(s string) error {
+	set, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	if set {
+		*t.traversal = t.value
+	}
+	return nil
+}
+
+func (t *traversalFlag) Type() string {
+	return "bool"
+}
+
+// Register -H, -L and -P on a flag set and return the selected traversal:
+// "H", "L", "P", or "" when none was given
+func traversalFlags(cmd *flag.FlagSet) *string {
+	traversal := new(string)
+	cmd.VarPF(&traversalFlag{traversal, "P"}, "physical", "P", "do not traverse any symbolic links").NoOptDefVal = "true"
+	cmd.VarPF(&traversalFlag{traversal, "L"}, "logical", "L", "traverse every symbolic link to a directory encountered").NoOptDefVal = "true"
+	cmd.VarPF(&traversalFlag{traversal, "H"}, "Hybrid", "H", "if a command line argument is a symbolic link to a directory, traverse it").NoOptDefVal = "true"
+	return traversal
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Check the permission bits of path, without following a symbolic link
+func chmodTestMode(t *testing.T, path string, want os.FileMode) {
+	t.Helper()
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != want {
+		t.Errorf("%s has mode %o, want %o", path, info.Mode().Perm(), want)
+	}
+}
+
+func TestChmodRecursiveSymlinks(t *testing.T) {
+	root := t.TempDir()
+	target, outside := filepath.Join(root, "target"), filepath.Join(root, "outside")
+	for _, dir := range []string{filepath.Join(target, "sub"), outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{filepath.Join(target, "sub", "f"), filepath.Join(outside, "o")} {
+		if err := os.WriteFile(file, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(outside, "o"), filepath.Join(target, "sub", "lnk")); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	// The command line link is followed and walked, the inner one is skipped
+	if err := Chmod("750", []string{link}, true, "", false, false, false, true); err != nil {
+		t.Fatal(err)
+	}
+	chmodTestMode(t, target, 0750)
+	chmodTestMode(t, filepath.Join(target, "sub"), 0750)
+	chmodTestMode(t, filepath.Join(target, "sub", "f"), 0750)
+	chmodTestMode(t, filepath.Join(outside, "o"), 0600)
+}
@@ -0,0 +1,246 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+const cpBenchSize = 64 << 20
+
+// Create a file of cpBenchSize bytes. A sparse file only has a few 1 MiB data
+// segments, a dense one is filled with random bytes
+func cpBenchFile(b *testing.B, sparse bool) string {
+	b.Helper()
+	path := filepath.Join(b.TempDir(), "src")
+	f, err := os.Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+
+	chunk := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(chunk)
+	for off := int64(0); off < cpBenchSize; off += int64(len(chunk)) {
+		if sparse && off%(16<<20) != 0 {
+			continue
+		}
+		if _, err := f.WriteAt(chunk, off); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := f.Truncate(cpBenchSize); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+// Copy src into a fresh destination b.N times with the given options
+func cpBenchCopy(b *testing.B, src string, opts cpOptions) {
+	in, err := os.Open(src)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer in.Close()
+	dst := filepath.Join(filepath.Dir(src), "dst")
+
+	b.SetBytes(cpBenchSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+
+		err = cpCopyData(out, in, opts)
+		out.Close()
+		if errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.EXDEV) || errors.Is(err, unix.EINVAL) {
+			b.Skipf("cloning is not supported here: %v", err)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCpReflink(b *testing.B) {
+	cpBenchCopy(b, cpBenchFile(b, false), cpOptions{reflink: "always", sparse: "auto"})
+}
+
+func BenchmarkCpCopyFileRange(b *testing.B) {
+	cpBenchCopy(b, cpBenchFile(b, false), cpOptions{reflink: "never", sparse: "auto"})
+}
+
+func BenchmarkCpSparseAuto(b *testing.B) {
+	cpBenchCopy(b, cpBenchFile(b, true), cpOptions{reflink: "never", sparse: "auto"})
+}
+
+func BenchmarkCpSparseAlwaysDense(b *testing.B) {
+	cpBenchCopy(b, cpBenchFile(b, false), cpOptions{reflink: "never", sparse: "always"})
+}
+
+func BenchmarkCpSparseAlwaysSparse(b *testing.B) {
+	cpBenchCopy(b, cpBenchFile(b, true), cpOptions{reflink: "never", sparse: "always"})
+}
+
+func BenchmarkCpReadWriteDense(b *testing.B) {
+	cpBenchCopy(b, cpBenchFile(b, false), cpOptions{reflink: "never", sparse: "never"})
+}
+
+func BenchmarkCpReadWriteSparse(b *testing.B) {
+	cpBenchCopy(b, cpBenchFile(b, true), cpOptions{reflink: "never", sparse: "never"})
+}
+
+func TestCpNewFileMode(t *testing.T) {
+	root := t.TempDir()
+	src, dst := filepath.Join(root, "s.sh"), filepath.Join(root, "s2.sh")
+	if err := os.WriteFile(src, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cpCopyFile(src, dst, cpOptions{sparse: "auto", reflink: "auto"}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := os.FileMode(0755 &^ getUmask()); info.Mode().Perm() != want {
+		t.Errorf("%s has mode %o, want %o", dst, info.Mode().Perm(), want)
+	}
+}
+
+func TestCpProgressNonRegular(t *testing.T) {
+	// A FIFO goes through the io.TeeReader path of cpCopyData
+	root := t.TempDir()
+	fifo := filepath.Join(root, "fifo")
+	if err := unix.Mkfifo(fifo, 0644); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		f, err := os.OpenFile(fifo, os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+		f.Write(make([]byte, 1000))
+		f.Close()
+	}()
+
+	var last Progress
+	opts := cpOptions{sparse: "auto", reflink: "never"}
+	opts.progress = newProgressWriter(nil, 1000, 1, func(p Progress) { last = p })
+
+	src, err := os.Open(fifo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	dst, err := os.Create(filepath.Join(root, "dst"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	if err := cpCopyData(dst, src, opts); err != nil {
+		t.Fatal(err)
+	}
+	opts.progress.finish()
+	if last.Bytes != 1000 {
+		t.Errorf("progress counted %d bytes, want 1000", last.Bytes)
+	}
+}
+
+func TestCpKeepExistingOnError(t *testing.T) {
+	root := t.TempDir()
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+	if err := os.WriteFile(src, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Cloning fails on filesystems without reflink support
+	err := cpCopyFile(src, dst, cpOptions{sparse: "auto", reflink: "always"})
+	if err == nil {
+		t.Skip("cloning is supported here")
+	}
+	if _, err := os.Lstat(dst); err != nil {
+		t.Errorf("existing destination was removed after a failed copy: %v", err)
+	}
+
+	// A destination created by the failed copy is cleaned up
+	os.Remove(dst)
+	if err := cpCopyFile(src, dst, cpOptions{sparse: "auto", reflink: "always"}); err == nil {
+		t.Fatal("second clone succeeded")
+	}
+	if _, err := os.Lstat(dst); !os.IsNotExist(err) {
+		t.Errorf("destination created by a failed copy was kept: %v", err)
+	}
+}
+
+func TestCpNonRegularDest(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src")
+	if err := os.WriteFile(src, make([]byte, 300000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+
+	// A pipe has no offsets and cannot be truncated
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	done := make(chan int64)
+	go func() {
+		n, _ := io.Copy(io.Discard, r)
+		done <- n
+	}()
+
+	for _, sparse := range []string{"auto", "always", "never"} {
+		if err := cpCopyData(w, in, cpOptions{sparse: sparse, reflink: "auto"}); err != nil {
+			t.Fatalf("--sparse=%s: %v", sparse, err)
+		}
+		in.Seek(0, io.SeekStart)
+	}
+	w.Close()
+	if n := <-done; n != 3*300000 {
+		t.Errorf("pipe received %d bytes, want %d", n, 3*300000)
+	}
+}
+
+func TestCpProcFile(t *testing.T) {
+	// procfs reports a size of 0 for files that have content
+	const src = "/proc/self/cmdline"
+	want, err := os.ReadFile(src)
+	if err != nil || len(want) == 0 {
+		t.Skipf("cannot read %s: %v", src, err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "cmdline")
+	if err := cpCopyFile(src, dst, cpOptions{sparse: "auto", reflink: "auto"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s holds %q, want %q", dst, got, want)
+	}
+}
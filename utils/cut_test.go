@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCutCharactersReplacementChar(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+		opts              cutOptions
+	}{
+		{"U+FFFD is one character", "x�y\n", "�\n", cutOptions{useChar: true}},
+		{"invalid byte is one character", "x\xffy\n", "\xff\n", cutOptions{useChar: true}},
+		{"-n keeps U+FFFD whole", "x�y\n", "�\n", cutOptions{useByte: true, noSplit: true}},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		w := bufio.NewWriter(&out)
+		opts := test.opts
+		opts.ranges = [][2]int{{1, 2}}
+		if opts.noSplit {
+			opts.ranges = [][2]int{{1, 4}}
+		}
+		opts.eol = "\n"
+
+		if err := cutLines(strings.NewReader(test.input), "test", w, opts); err != nil {
+			t.Fatal(err)
+		}
+		w.Flush()
+		if out.String() != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, out.String(), test.want)
+		}
+	}
+}
+
+func TestCutBytesNoSplit(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+		ranges            [][2]int
+	}{
+		{"range inside a character selects it", "é…\n", "é\n", [][2]int{{1, 2}}},
+		{"range ending inside a character drops it", "é…\n", "é\n", [][2]int{{0, 3}}},
+		{"range ending on the last byte keeps it", "é…\n", "é…\n", [][2]int{{0, 5}}},
+		{"single byte characters", "abc\n", "b\n", [][2]int{{1, 2}}},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		w := bufio.NewWriter(&out)
+		opts := cutOptions{ranges: test.ranges, useByte: true, noSplit: true, eol: "\n"}
+
+		if err := cutLines(strings.NewReader(test.input), "test", w, opts); err != nil {
+			t.Fatal(err)
+		}
+		w.Flush()
+		if out.String() != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, out.String(), test.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package mode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parses an octal or symbolic mode and applies it to the current mode bits.
+// Symbolic modes are comma separated clauses of [ugoa...][+-=][rwxXst...] or
+// [ugoa...][+-=][ugo], the latter copying the bits of another class. Clauses
+// without a who part do not change the bits set in umask (Mkdir, Chmod)
+func Parse(expr string, current uint32, isDir bool, umask uint32) (uint32, error) {
+	if expr == "" {
+		return 0, fmt.Errorf("Invalid mode: ''")
+	}
+
+	if strings.Trim(expr, "01234567") == "" {
+		mode, err := strconv.ParseUint(expr, 8, 32)
+		if err != nil || mode > 07777 {
+			return 0, fmt.Errorf("Invalid mode: '%s'", expr)
+		}
+		return uint32(mode), nil
+	}
+
+	mode := current & 07777
+	for _, clause := range strings.Split(expr, ",") {
+		i := 0
+
+		var who uint32
+		for ; i < len(clause) && strings.IndexByte("ugoa", clause[i]) >= 0; i++ {
+			switch clause[i] {
+			case 'u':
+				who |= 04700
+			case 'g':
+				who |= 02070
+			case 'o':
+				who |= 01007
+			case 'a':
+				who |= 07777
+			}
+		}
+
+		noWho := who == 0
+		if noWho {
+			who = 07777
+		}
+
+		if i == len(clause) {
+			return 0, fmt.Errorf("Invalid mode: '%s'", expr)
+		}
+
+		for i < len(clause) {
+			op := clause[i]
+			if op != '+' && op != '-' && op != '=' {
+				return 0, fmt.Errorf("Invalid mode: '%s'", expr)
+			}
+			i++
+
+			var perm uint32
+			if i < len(clause) && strings.IndexByte("ugo", clause[i]) >= 0 {
+				var bits uint32
+				switch clause[i] {
+				case 'u':
+					bits = mode >> 6 & 7
+				case 'g':
+					bits = mode >> 3 & 7
+				case 'o':
+					bits = mode & 7
+				}
+				perm = bits<<6 | bits<<3 | bits
+				i++
+			} else {
+				for ; i < len(clause) && strings.IndexByte("rwxXst", clause[i]) >= 0; i++ {
+					switch clause[i] {
+					case 'r':
+						perm |= 0444
+					case 'w':
+						perm |= 0222
+					case 'x':
+						perm |= 0111
+					case 'X':
+						if isDir || mode&0111 != 0 {
+							perm |= 0111
+						}
+					case 's':
+						perm |= 06000
+					case 't':
+						perm |= 01000
+					}
+				}
+			}
+
+			perm &= who
+			if noWho {
+				perm &^= umask
+			}
+
+			switch op {
+			case '+':
+				mode |= perm
+			case '-':
+				mode &^= perm
+			case '=':
+				mode = mode&^who | perm
+			}
+		}
+	}
+	return mode, nil
+}
@@ -0,0 +1,37 @@
+package mode
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr    string
+		current uint32
+		isDir   bool
+		umask   uint32
+		want    uint32
+	}{
+		{"755", 0, false, 0, 0755},
+		{"u+x", 0644, false, 0, 0744},
+		{"go-w", 0666, false, 0, 0644},
+		{"a=r,u+w", 0777, false, 0, 0644},
+		{"+w", 0444, false, 022, 0644},
+		{"a+X", 0644, true, 0, 0755},
+		{"a+X", 0644, false, 0, 0644},
+		{"g=u", 0700, false, 0, 0770},
+		{"u+s,+t", 0755, false, 0, 05755},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.expr, tt.current, tt.isDir, tt.umask)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+		} else if got != tt.want {
+			t.Errorf("Parse(%q, %o) = %o, want %o", tt.expr, tt.current, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"", "8", "17777", "u", "u*x", "rwx"} {
+		if _, err := Parse(expr, 0644, false, 0); err == nil {
+			t.Errorf("Parse(%q) succeeded", expr)
+		}
+	}
+}
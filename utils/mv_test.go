@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Create a file with the given content and its parent directories
+func mvTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Check that path holds content
+func mvTestContent(t *testing.T, path, content string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Errorf("%s holds %q, want %q", path, data, content)
+	}
+}
+
+func mvTest(files []string, noClobber, noTargetDir bool, targetDir string) error {
+	return Mv(files, false, false, noClobber, false, false, noTargetDir, targetDir, "", "", false, nil)
+}
+
+func TestMvIntoItself(t *testing.T) {
+	root := t.TempDir()
+	d := filepath.Join(root, "d")
+	mvTestFile(t, filepath.Join(d, "sub", "f"), "f")
+
+	if err := mvTest([]string{d, filepath.Join(d, "sub")}, false, false, ""); err == nil {
+		t.Fatal("moving a directory into its subdirectory succeeded")
+	}
+	if err := mvTest([]string{d, d}, false, false, ""); err == nil {
+		t.Fatal("moving a directory into itself succeeded")
+	}
+	mvTestContent(t, filepath.Join(d, "sub", "f"), "f")
+}
+
+func TestMvBasename(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "a", "b.txt")
+	dir := filepath.Join(root, "dir")
+	mvTestFile(t, src, "b")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mvTest([]string{src, dir + "/"}, false, false, ""); err != nil {
+		t.Fatal(err)
+	}
+	mvTestContent(t, filepath.Join(dir, "b.txt"), "b")
+	if _, err := os.Lstat(src); !os.IsNotExist(err) {
+		t.Errorf("%s still exists", src)
+	}
+}
+
+func TestMvTargetDirectory(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "dir")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	x, y := filepath.Join(root, "x", "one"), filepath.Join(root, "y", "two")
+	mvTestFile(t, x, "1")
+	mvTestFile(t, y, "2")
+
+	if err := mvTest([]string{x, y}, false, false, dir); err != nil {
+		t.Fatal(err)
+	}
+	mvTestContent(t, filepath.Join(dir, "one"), "1")
+	mvTestContent(t, filepath.Join(dir, "two"), "2")
+
+	if err := mvTest([]string{filepath.Join(dir, "one")}, false, false, filepath.Join(dir, "one")); err == nil {
+		t.Error("-t with a non-directory succeeded")
+	}
+}
+
+func TestMvNoTargetDirectory(t *testing.T) {
+	root := t.TempDir()
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+	mvTestFile(t, filepath.Join(src, "f"), "f")
+	if err := os.Mkdir(dst, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// With -T an empty directory is replaced instead of entered
+	if err := mvTest([]string{src, dst}, false, true, ""); err != nil {
+		t.Fatal(err)
+	}
+	mvTestContent(t, filepath.Join(dst, "f"), "f")
+	if _, err := os.Lstat(filepath.Join(dst, "src")); !os.IsNotExist(err) {
+		t.Error("-T moved the source inside the target")
+	}
+
+	if err := mvTest([]string{filepath.Join(dst, "f"), root, dst}, false, true, ""); err == nil {
+		t.Error("-T with more than two operands succeeded")
+	}
+}
+
+func TestMvNoClobber(t *testing.T) {
+	root := t.TempDir()
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+	mvTestFile(t, src, "new")
+	mvTestFile(t, dst, "old")
+
+	if err := mvTest([]string{src, dst}, true, false, ""); err != nil {
+		t.Fatal(err)
+	}
+	mvTestContent(t, dst, "old")
+	mvTestContent(t, src, "new")
+}
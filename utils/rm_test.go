@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// Build root/tree/inner with enough entries to keep the walk busy
+func rmTestTree(t *testing.T, tree string) {
+	t.Helper()
+	for i := 0; i < 20; i++ {
+		dir := filepath.Join(tree, "inner", fmt.Sprintf("d%d", i))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 20; j++ {
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d", j)), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestRmRecursiveSymlinkSwap(t *testing.T) {
+	root := t.TempDir()
+	outside := filepath.Join(root, "outside")
+	if err := os.MkdirAll(filepath.Join(outside, "d0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	keep := []string{filepath.Join(outside, "keep"), filepath.Join(outside, "d0", "f0")}
+	for _, file := range keep {
+		if err := os.WriteFile(file, []byte("keep"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for round := 0; round < 20; round++ {
+		tree := filepath.Join(root, fmt.Sprintf("tree%d", round))
+		rmTestTree(t, tree)
+		inner := filepath.Join(tree, "inner")
+
+		// Swap the inner directory for a symlink to outside while the walk runs
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for i := 0; i < 100; i++ {
+				if os.Rename(inner, inner+".old") == nil {
+					os.Symlink(outside, inner)
+					return
+				}
+			}
+		}()
+
+		close(start)
+		// Errors are expected when the swap races with the walk, only the result matters
+		rmRecursive(tree, "never")
+		wg.Wait()
+		rmRecursive(tree, "never")
+
+		for _, file := range keep {
+			if _, err := os.Stat(file); err != nil {
+				t.Fatalf("round %d: %s was removed through the symlink: %v", round, file, err)
+			}
+		}
+		if _, err := os.Lstat(tree); !os.IsNotExist(err) {
+			t.Fatalf("round %d: %s was not removed: %v", round, tree, err)
+		}
+	}
+}
+
+func TestRmRecursiveSymlinkRoot(t *testing.T) {
+	root := t.TempDir()
+	outside := filepath.Join(root, "outside")
+	if err := os.Mkdir(outside, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "keep"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rmRecursive(link, "never"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(link); !os.IsNotExist(err) {
+		t.Errorf("symlink %s was not removed", link)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "keep")); err != nil {
+		t.Errorf("file behind the symlink was removed: %v", err)
+	}
+}
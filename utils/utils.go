@@ -5,9 +5,11 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	fileMode "gocore/utils/mode"
 	"io"
 	"io/fs"
 	"log"
+	"net/url"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -20,6 +22,11 @@ import (
 	"text/tabwriter"
 	"time"
 	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
 )
 
 // This struct is used to save each file informations (Ls)
@@ -47,6 +54,164 @@ func columnise(w *tabwriter.Writer, opt []string) {
 	}
 }
 
+// Progress of a transfer, sent to progress callbacks (Cp, Mv, Cat)
+type Progress struct {
+	File              string
+	Bytes, Total      int64
+	Files, TotalFiles int
+	Elapsed           time.Duration
+	Done              bool
+}
+
+// Counting writer shared by the copy loops. Bytes written through it, or
+// added for copies the kernel does on its own, are reported to the callback
+// at most every 100ms. Without w the bytes are only counted, which is how the
+// cp engine counts data it reads through io.TeeReader. A nil progressWriter
+// discards what is written to it (Cp, Mv, Cat)
+type progressWriter struct {
+	w           io.Writer
+	callback    func(Progress)
+	state       Progress
+	start, last time.Time
+}
+
+func newProgressWriter(w io.Writer, total int64, totalFiles int, callback func(Progress)) *progressWriter {
+	if callback == nil {
+		return nil
+	}
+	return &progressWriter{
+		w:        w,
+		callback: callback,
+		state:    Progress{Total: total, TotalFiles: totalFiles},
+		start:    time.Now(),
+	}
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	if p == nil {
+		return len(b), nil
+	}
+
+	n, err := len(b), error(nil)
+	if p.w != nil {
+		n, err = p.w.Write(b)
+	}
+	p.add(int64(n))
+	return n, err
+}
+
+// Count bytes copied without going through Write
+func (p *progressWriter) add(n int64) {
+	if p == nil {
+		return
+	}
+	p.state.Bytes += n
+	p.report(false)
+}
+
+func (p *progressWriter) startFile(name string) {
+	if p == nil {
+		return
+	}
+	p.state.File = name
+	p.report(false)
+}
+
+func (p *progressWriter) doneFile() {
+	if p == nil {
+		return
+	}
+	p.state.Files++
+	p.report(false)
+}
+
+// Count files moved without copying their data
+func (p *progressWriter) skip(bytes int64, files int) {
+	if p == nil {
+		return
+	}
+	p.state.Bytes += bytes
+	p.state.Files += files
+	p.report(false)
+}
+
+// Send the last event, with Done set
+func (p *progressWriter) finish() {
+	if p == nil {
+		return
+	}
+	p.state.Done = true
+	p.report(true)
+}
+
+func (p *progressWriter) report(force bool) {
+	now := time.Now()
+	if !force && now.Sub(p.last) < 100*time.Millisecond {
+		return
+	}
+	p.last = now
+	p.state.Elapsed = now.Sub(p.start)
+	p.callback(p.state)
+}
+
+// Total size and number of the regular files under paths (Cp, Mv, Cat)
+func progressTotals(paths []string) (int64, int) {
+	var size int64
+	var count int
+	for _, path := range paths {
+		filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			info, err := os.Stat(path)
+			if err == nil && info.Mode().IsRegular() {
+				size += info.Size()
+				count++
+			}
+			return nil
+		})
+	}
+	return size, count
+}
+
+// Format a byte count with binary units, e.g. 1.5 MiB
+func humanSize(n float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	i := 0
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", n, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", n, units[i])
+}
+
+// ProgressBar returns a progress callback that draws bytes copied, rate, ETA
+// and file count on stderr, or nil when stderr is not a terminal
+func ProgressBar() func(Progress) {
+	if !isTerminal(os.Stderr) {
+		return nil
+	}
+
+	return func(p Progress) {
+		rate := float64(p.Bytes) / max(p.Elapsed.Seconds(), 0.001)
+		eta := "--:--"
+		if p.Total > p.Bytes && rate > 0 {
+			left := time.Duration(float64(p.Total-p.Bytes) / rate * float64(time.Second))
+			eta = fmt.Sprintf("%02d:%02d", int(left.Minutes()), int(left.Seconds())%60)
+		} else if p.Total > 0 {
+			eta = "00:00"
+		}
+
+		fmt.Fprintf(os.Stderr, "\r\033[K%s / %s  %s/s  ETA %s  %d/%d files", humanSize(float64(p.Bytes)), humanSize(float64(p.Total)), humanSize(rate), eta, p.Files, p.TotalFiles)
+		if p.Done {
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+}
+
 // Prints n bytes of a file (Cat)
 func catBytePrinter(file string) error {
 	files, err := os.Open(file)
@@ -195,85 +360,242 @@ func tailLinePrinter(f *os.File, linesString string) error {
 	return nil
 }
 
-// Prompts a confirmation message (Rm)
-func promptFile(path string) bool {
-	var answer string
-	fmt.Printf("rm: remove '%s'? ", path)
+// Shared reader for interactive prompts. Keeping a single buffered reader
+// means answers typed ahead are not lost between prompts
+var promptReader = bufio.NewReader(os.Stdin)
 
-	scanner := bufio.NewScanner(os.Stdin)
-	if scanner.Scan() {
-		answer = strings.ToLower(scanner.Text())
-	}
+// Writes a question to stderr and reads a yes/no answer from stdin (Rm, Mv, Cp)
+func promptYes(format string, a ...any) bool {
+	fmt.Fprintf(os.Stderr, format, a...)
 
-	if answer == "y" || answer == "yes" {
-		return true
+	answer, err := promptReader.ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(os.Stderr)
+		return false
 	}
 
-	fmt.Printf("rm: File '%s' not removed\n", path)
-	return false
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
 }
 
-// Verifies if a file is Read-Only
-func isReadOnly(path string) (bool, error) {
-	info, err := os.Stat(path)
-	if err != nil {
-		return false, err
+// Check if a file is a terminal
+func isTerminal(f *os.File) bool {
+	_, err := unix.IoctlGetTermios(int(f.Fd()), unix.TCGETS)
+	return err == nil
+}
+
+// Describes the type of a file the way rm prompts do, e.g. "write-protected regular empty file" (Rm)
+func describeFile(stat *unix.Stat_t, writeProtected bool) string {
+	var desc string
+	switch stat.Mode & unix.S_IFMT {
+	case unix.S_IFDIR:
+		desc = "directory"
+	case unix.S_IFLNK:
+		desc = "symbolic link"
+	case unix.S_IFIFO:
+		desc = "fifo"
+	case unix.S_IFSOCK:
+		desc = "socket"
+	case unix.S_IFCHR:
+		desc = "character special file"
+	case unix.S_IFBLK:
+		desc = "block special file"
+	default:
+		if stat.Size == 0 {
+			desc = "regular empty file"
+		} else {
+			desc = "regular file"
+		}
 	}
 
-	perm := info.Mode().Perm()
+	if writeProtected {
+		return "write-protected " + desc
+	}
+	return desc
+}
+
+// Decides whether an entry relative to parentFd should be removed, prompting
+// according to the interactive mode: "always" prompts for every entry, the
+// default ("") only for write-protected entries when stdin is a terminal (Rm)
+func rmConfirm(parentFd int, name, path string, stat *unix.Stat_t, interactive string) bool {
+	writeProtected := stat.Mode&unix.S_IFMT != unix.S_IFLNK && unix.Faccessat(parentFd, name, unix.W_OK, 0) != nil
+
+	switch {
+	case interactive == "always":
+	case interactive == "" && writeProtected && isTerminal(os.Stdin):
+	default:
+		return true
+	}
 
-	isProtected := perm&0200 == 0
+	return promptYes("rm: remove %s '%s'? ", describeFile(stat, writeProtected), path)
+}
 
-	return isProtected, nil
+// Current process umask
+func getUmask() int {
+	umask := unix.Umask(0)
+	unix.Umask(umask)
+	return umask
 }
 
-// Make parents of a dir (Mkdir)
-func mkdirParents(perm int, files string) error {
+// Make the missing parents of a dir, like os.MkdirAll but without the final
+// component. As POSIX requires, parents get 0777 minus the umask plus u+wx.
+// Returns the directories created, outermost first (Mkdir)
+func mkdirParents(dir string, umask int) ([]string, error) {
+	var missing []string
+	for p := filepath.Dir(filepath.Clean(dir)); ; p = filepath.Dir(p) {
+		info, err := os.Stat(p)
+		if err == nil {
+			if !info.IsDir() {
+				return nil, fmt.Errorf("Cannot create directory '%s': '%s' is not a directory", dir, p)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("Cannot create directory '%s': %w", dir, err)
+		}
 
-	dir := strings.Split(files, "/")
-	for _, file := range dir {
-		err := os.Mkdir(file, os.FileMode(perm))
-		if err != nil && os.IsExist(err) {
-			return fmt.Errorf("Directory '%s' already exists.", file)
+		missing = append(missing, p)
+		if p == filepath.Dir(p) {
+			break
+		}
+	}
+
+	var created []string
+	perm := uint32(0777&^umask | 0300)
+	for i := len(missing) - 1; i >= 0; i-- {
+		err := unix.Mkdir(missing[i], perm)
+		if errors.Is(err, unix.EEXIST) {
+			continue
 		}
-		err = os.Chdir(file)
 		if err != nil {
-			return fmt.Errorf("Error changing directory: %w", err)
+			return created, fmt.Errorf("Error creating '%s': %w", missing[i], err)
+		}
+
+		// The umask may have cleared u+wx, so set the mode explicitly
+		if err := unix.Chmod(missing[i], perm); err != nil {
+			return created, fmt.Errorf("Error changing '%s' mode: %w", missing[i], err)
 		}
+		created = append(created, missing[i])
+	}
+	return created, nil
+}
 
+// Set the SELinux security context of a file (Mkdir)
+func setSecurityContext(path string, context string) error {
+	if err := unix.Lsetxattr(path, "security.selinux", append([]byte(context), 0), 0); err != nil {
+		return fmt.Errorf("Error setting security context of '%s': %w", path, err)
 	}
 	return nil
 }
 
-// Remove files recursively
-func rmRecursive(dir string, interactive bool, force bool) error {
-	itens, err := os.ReadDir(dir)
+// Check if the kernel has SELinux enabled
+func selinuxEnabled() bool {
+	var stat unix.Statfs_t
+	return unix.Statfs("/sys/fs/selinux", &stat) == nil && stat.Type == unix.SELINUX_MAGIC
+}
+
+// Remove files recursively (Rm)
+//
+// The tree is walked with directory file descriptors (openat with
+// O_NOFOLLOW|O_DIRECTORY and unlinkat) instead of joined path strings, so a
+// directory swapped for a symbolic link during the walk is never followed.
+func rmRecursive(dir string, interactive string) error {
+	dir = filepath.Clean(dir)
+
+	parentFd, err := unix.Open(filepath.Dir(dir), unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
 	if err != nil {
-		return fmt.Errorf("cannot read dir %s: %w", dir, err)
+		return fmt.Errorf("cannot open dir %s: %w", filepath.Dir(dir), err)
 	}
+	defer unix.Close(parentFd)
 
-	for _, item := range itens {
-		fullPath := filepath.Join(dir, item.Name())
+	_, err = rmRecursiveAt(parentFd, filepath.Base(dir), dir, interactive)
+	return err
+}
 
-		if item.IsDir() {
-			err := rmRecursive(fullPath, interactive, force)
-			if err != nil {
-				return fmt.Errorf("Error at %s: %v\n", fullPath, err)
-			}
-			os.Remove(fullPath)
+// Remove the entry name relative to parentFd and, if it is a directory, all of
+// its contents. Reports whether the entry was removed (Rm)
+func rmRecursiveAt(parentFd int, name, path string, interactive string) (bool, error) {
+	fd, err := unix.Openat(parentFd, name, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		if errors.Is(err, unix.ENOTDIR) || errors.Is(err, unix.ELOOP) {
+			// Not a directory (anymore): remove the entry itself, never its target
+			return rmFileAt(parentFd, name, path, interactive)
+		}
+		return false, fmt.Errorf("cannot open dir %s: %w", path, err)
+	}
+
+	dirFile := os.NewFile(uintptr(fd), path)
+	names, err := dirFile.Readdirnames(-1)
+	if err != nil {
+		dirFile.Close()
+		return false, fmt.Errorf("cannot read dir %s: %w", path, err)
+	}
+
+	if len(names) > 0 && interactive == "always" {
+		if !promptYes("rm: descend into directory '%s'? ", path) {
+			dirFile.Close()
+			return false, nil
+		}
+	}
+
+	removedAll := true
+	for _, item := range names {
+		fullPath := filepath.Join(path, item)
+
+		var stat unix.Stat_t
+		if err := unix.Fstatat(fd, item, &stat, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+			dirFile.Close()
+			return false, fmt.Errorf("cannot stat %s: %w", fullPath, err)
+		}
+
+		var removed bool
+		if stat.Mode&unix.S_IFMT == unix.S_IFDIR {
+			removed, err = rmRecursiveAt(fd, item, fullPath, interactive)
 		} else {
-			readOnly, _ := isReadOnly(fullPath)
-			if (interactive || readOnly) && !force {
-				if promp := promptFile(fullPath); !promp {
-					continue
-				}
-			}
-			os.Remove(fullPath)
+			removed, err = rmFileAt(fd, item, fullPath, interactive)
+		}
+		if err != nil {
+			dirFile.Close()
+			return false, fmt.Errorf("Error at %s: %w", fullPath, err)
 		}
+		removedAll = removedAll && removed
 	}
+	dirFile.Close()
 
-	return nil
+	// A declined prompt leaves the directory non-empty, so keep it as well
+	if !removedAll {
+		return false, nil
+	}
+
+	var stat unix.Stat_t
+	if err := unix.Fstatat(parentFd, name, &stat, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+		return false, fmt.Errorf("cannot stat %s: %w", path, err)
+	}
+	if !rmConfirm(parentFd, name, path, &stat, interactive) {
+		return false, nil
+	}
+
+	if err := unix.Unlinkat(parentFd, name, unix.AT_REMOVEDIR); err != nil {
+		return false, fmt.Errorf("Error removing '%s': %w", path, err)
+	}
+	return true, nil
+}
+
+// Remove a non-directory entry relative to parentFd, prompting when needed (Rm)
+func rmFileAt(parentFd int, name, path string, interactive string) (bool, error) {
+	var stat unix.Stat_t
+	if err := unix.Fstatat(parentFd, name, &stat, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+		return false, fmt.Errorf("cannot stat %s: %w", path, err)
+	}
+
+	if !rmConfirm(parentFd, name, path, &stat, interactive) {
+		return false, nil
+	}
 
+	if err := unix.Unlinkat(parentFd, name, 0); err != nil {
+		return false, fmt.Errorf("Error removing '%s': %w", path, err)
+	}
+	return true, nil
 }
 
 // append indicator (one of /*@|) to entries (Ls)
@@ -313,30 +635,7 @@ func fileExists(filePath string) bool {
 
 // Prompt for mv (Mv)
 func mvPrompt(file string) bool {
-	var answer string
-	fmt.Printf("mv: Overwrite '%s'? ", file)
-
-	scanner := bufio.NewScanner(os.Stdin)
-	if scanner.Scan() {
-		answer = strings.ToLower(scanner.Text())
-	}
-
-	if !(answer == "y") && !(answer == "yes") {
-		return false
-	}
-	return true
-}
-
-// Ln force (-f) option (Ln)
-func lnForce(file string) error {
-	if exist := fileExists(file); exist {
-		err := os.Remove(file)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return promptYes("mv: overwrite '%s'? ", file)
 }
 
 // Check if the path is a folder
@@ -485,6 +784,41 @@ func lsFormatLine(i fileInfoStruct, omitOwner, omitGroup, kiloSize, ctime, numer
 	)
 }
 
+// Return the string comparison of the current collation locale, taken from
+// LC_ALL, LC_COLLATE or LANG. The C and POSIX locales, and locales that cannot
+// be parsed, compare bytes. Strings that collate equal are ordered by their
+// bytes, so distinct strings never compare equal (Comm, Ls)
+func localeCompare() func(a, b string) int {
+	locale := os.Getenv("LC_ALL")
+	if locale == "" {
+		locale = os.Getenv("LC_COLLATE")
+	}
+	if locale == "" {
+		locale = os.Getenv("LANG")
+	}
+
+	// Drop the codeset and modifier, as in en_US.UTF-8@euro
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	if locale == "" || locale == "C" || locale == "POSIX" {
+		return strings.Compare
+	}
+
+	tag, err := language.Parse(strings.ReplaceAll(locale, "_", "-"))
+	if err != nil {
+		return strings.Compare
+	}
+
+	c := collate.New(tag)
+	return func(a, b string) int {
+		if r := c.CompareString(a, b); r != 0 {
+			return r
+		}
+		return strings.Compare(a, b)
+	}
+}
+
 // Responsible to prepare and print the output according to the chosen ls flags (Ls)
 func lsPrinter(dir string, almostAllDir, allDir, classify, column, longListing, sortSize, kiloSize, streamFormat, omitOwner, omitGroup, ctime, numericUidGid, inode, dereference, onePerLine, sortmTime, indicatorStyle, hideControlChars, reverse, accessTime, noSort bool, files []os.DirEntry) {
 	var result []string
@@ -501,11 +835,12 @@ func lsPrinter(dir string, almostAllDir, allDir, classify, column, longListing,
 
 	}
 	if !noSort {
+		compare := localeCompare()
 		sort.Slice(result, func(i, j int) bool {
 			if reverse {
-				return strings.ToLower(result[i]) > strings.ToLower(result[j])
+				return compare(result[i], result[j]) > 0
 			} else {
-				return strings.ToLower(result[i]) < strings.ToLower(result[j])
+				return compare(result[i], result[j]) < 0
 			}
 		})
 	}
@@ -646,55 +981,158 @@ func Ls(dirs []string, almostAllDir, column, classify, recursive, allDir, longLi
 	return nil
 }
 
-func Mkdir(perm int, parents bool, dir []string) error {
+func Mkdir(mode string, parents bool, verbose bool, context string, dir []string) error {
+	umask := getUmask()
+
+	// Symbolic modes are relative to a=rwx
+	perm := -1
+	if mode != "" {
+		parsed, err := fileMode.Parse(mode, 0777, true, uint32(umask))
+		if err != nil {
+			return err
+		}
+		perm = int(parsed)
+	}
+
+	// Without SELinux a bare -Z does nothing, as in GNU mkdir
+	if context != "" && !selinuxEnabled() {
+		if context != "default" {
+			fmt.Fprintln(os.Stderr, "mkdir: warning: ignoring --context; it requires an SELinux-enabled kernel")
+		}
+		context = ""
+	}
+
+	// Looking up the default type needs the policy's file contexts, which
+	// are not read here
+	if context == "default" {
+		return fmt.Errorf("Setting the default security context is not supported, use --context=CTX")
+	}
+
 	for _, files := range dir {
-		err := os.Mkdir(files, os.FileMode(perm))
+		if parents {
+			created, err := mkdirParents(files, umask)
+			if verbose {
+				for _, d := range created {
+					fmt.Printf("mkdir: created directory '%s'\n", d)
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		// Without -m the umask applies as usual
+		err := unix.Mkdir(files, 0777)
 
 		if err != nil {
 			switch {
-			case os.IsExist(err):
-				return fmt.Errorf("Directory '%s' already exists.", files)
-
-			case parents && os.IsNotExist(err):
-				err := mkdirParents(perm, files)
-				if err != nil {
-					return fmt.Errorf("%w", err)
+			case parents && errors.Is(err, unix.EEXIST):
+				if isDir, _ := isDirectory(files); isDir {
+					continue
 				}
+				return fmt.Errorf("Cannot create directory '%s': File exists", files)
+
+			case errors.Is(err, unix.EEXIST):
+				return fmt.Errorf("Directory '%s' already exists.", files)
 
 			default:
 				return fmt.Errorf("Error creating '%s': %w", files, err)
 			}
 		}
 
+		// With -m the final directory gets exactly the requested mode
+		if perm >= 0 {
+			if err := unix.Chmod(files, uint32(perm)); err != nil {
+				return fmt.Errorf("Error changing '%s' mode: %w", files, err)
+			}
+		}
+
+		if context != "" {
+			if err := setSecurityContext(files, context); err != nil {
+				return err
+			}
+		}
+
+		if verbose {
+			fmt.Printf("mkdir: created directory '%s'\n", files)
+		}
 	}
 	return nil
 }
 
-func Rm(interactive bool, force bool, recursive bool, dir []string) error {
+func Rm(interactive string, force bool, recursive bool, trash bool, dir []string) error {
+	switch interactive {
+	case "", "never", "once", "always":
+	default:
+		return fmt.Errorf("invalid argument '%s' for '--interactive'", interactive)
+	}
+
+	if force {
+		interactive = "never"
+	}
+
+	if interactive == "once" && (len(dir) > 3 || recursive) {
+		noun := "arguments"
+		if len(dir) == 1 {
+			noun = "argument"
+		}
+		how := ""
+		if recursive {
+			how = " recursively"
+		}
+		if !promptYes("rm: remove %d %s%s? ", len(dir), noun, how) {
+			return nil
+		}
+	}
 
 	for _, file := range dir {
+		if trash {
+			var stat unix.Stat_t
+			if err := unix.Lstat(file, &stat); err != nil {
+				if force && errors.Is(err, unix.ENOENT) {
+					continue
+				}
+				return fmt.Errorf("Error obtaining '%s' information: %w", file, err)
+			}
+
+			if stat.Mode&unix.S_IFMT == unix.S_IFDIR && !recursive {
+				return fmt.Errorf("Cannot remove '%s': Is a directory", file)
+			}
+
+			if !rmConfirm(unix.AT_FDCWD, file, file, &stat, interactive) {
+				continue
+			}
+
+			if err := trashFile(file); err != nil {
+				return fmt.Errorf("Error moving '%s' to the trash: %w", file, err)
+			}
+			continue
+		}
+
 		if recursive {
-			err := rmRecursive(file, interactive, force)
+			err := rmRecursive(file, interactive)
 			if err != nil {
+				if force && errors.Is(err, unix.ENOENT) {
+					continue
+				}
 				return fmt.Errorf("Error removing files recursively: %w", err)
 			}
-			err = os.Remove(file)
-			if err != nil {
-				return fmt.Errorf("Error removing '%s': %w", file, err)
-			}
 			continue
 		}
-		readOnly, err := isReadOnly(file)
-		if err != nil {
-			return fmt.Errorf("Error determining if '%s' is read-only: %w", file, err)
-		}
-		if (interactive || readOnly) && !force {
-			if promp := promptFile(file); !promp {
+
+		var stat unix.Stat_t
+		if err := unix.Lstat(file, &stat); err != nil {
+			if force && errors.Is(err, unix.ENOENT) {
 				continue
 			}
+			return fmt.Errorf("Error obtaining '%s' information: %w", file, err)
+		}
+
+		if !rmConfirm(unix.AT_FDCWD, file, file, &stat, interactive) {
+			continue
 		}
 
-		err = os.Remove(file)
+		err := os.Remove(file)
 		if err != nil && os.IsExist(err) && !force {
 			return fmt.Errorf("Directory '%s' is not empty.", file)
 		} else if err != nil && !os.IsExist(err) && !force {
@@ -704,35 +1142,412 @@ func Rm(interactive bool, force bool, recursive bool, dir []string) error {
 	return nil
 }
 
-func Cat(byte bool, files ...string) error {
-
-	for _, file := range files {
-		if byte {
-			catBytePrinter(file)
-			return nil
-		}
+// This struct is used to save each trashed file informations (Trash)
+type trashEntry struct {
+	name, trashDir, path string
+	deleted              time.Time
+}
 
-		data, err := os.ReadFile(file)
+// Home trash directory, $XDG_DATA_HOME/Trash (Trash)
+func trashHome() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
 		if err != nil {
-			return fmt.Errorf("cannot read the file %s: %w", file, err)
+			return "", fmt.Errorf("Cannot find home directory: %w", err)
 		}
-		os.Stdout.Write(data)
+		dataHome = filepath.Join(home, ".local", "share")
 	}
-	return nil
+	return filepath.Join(dataHome, "Trash"), nil
 }
 
-func Head(lines int, files ...string) error {
-	if lines <= 0 {
-		return fmt.Errorf("Error: number of lines is 0 or negative")
+// Device of a path, or of its nearest existing parent (Trash)
+func trashDevice(path string) (uint64, error) {
+	for {
+		var stat unix.Stat_t
+		err := unix.Stat(path, &stat)
+		if err == nil {
+			return stat.Dev, nil
+		}
+		if !errors.Is(err, unix.ENOENT) || path == filepath.Dir(path) {
+			return 0, err
+		}
+		path = filepath.Dir(path)
 	}
-	for _, file := range files {
-		f, err := os.Open(file)
-		if err != nil {
-			return fmt.Errorf("Error opening file '%s': %w", file, err)
+}
+
+// Top directory of the mount that holds dir (Trash)
+func trashTopDir(dir string, dev uint64) string {
+	for dir != "/" {
+		parent := filepath.Dir(dir)
+		var stat unix.Stat_t
+		if err := unix.Stat(parent, &stat); err != nil || stat.Dev != dev {
+			return dir
 		}
+		dir = parent
+	}
+	return dir
+}
 
-		scanner := bufio.NewScanner(f)
-		lineCount := 0
+// Picks the trash for a file in dir: the home trash when dir is on the same
+// filesystem, otherwise $topdir/.Trash-$UID of dir's mount. topDir is empty
+// for the home trash (Trash)
+func trashDirFor(dir string) (trashDir string, topDir string, err error) {
+	home, err := trashHome()
+	if err != nil {
+		return "", "", err
+	}
+
+	homeDev, err := trashDevice(home)
+	if err != nil {
+		return "", "", fmt.Errorf("Cannot get '%s' information: %w", home, err)
+	}
+
+	dev, err := trashDevice(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("Cannot get '%s' information: %w", dir, err)
+	}
+
+	if dev == homeDev {
+		return home, "", nil
+	}
+
+	topDir = trashTopDir(dir, dev)
+	return filepath.Join(topDir, ".Trash-"+strconv.Itoa(os.Getuid())), topDir, nil
+}
+
+// Check that a per-mount trash directory, or its files or info subdirectory,
+// is a real directory owned by the user. The spec forbids using anything else,
+// since another user could redirect trashed files with a symbolic link (Trash)
+func trashCheckDir(path string) error {
+	var stat unix.Stat_t
+	if err := unix.Lstat(path, &stat); err != nil {
+		return fmt.Errorf("Cannot get '%s' information: %w", path, err)
+	}
+	if stat.Mode&unix.S_IFMT != unix.S_IFDIR || int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("Refusing to use trash directory '%s': not a directory owned by the user", path)
+	}
+	return nil
+}
+
+// Moves a file into the trash and writes its .trashinfo metadata (Rm)
+func trashFile(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	// Resolve the parent only, a symbolic link is trashed as itself
+	parent, err := filepath.EvalSymlinks(filepath.Dir(abs))
+	if err != nil {
+		return err
+	}
+	abs = filepath.Join(parent, filepath.Base(abs))
+
+	trashDir, topDir, err := trashDirFor(parent)
+	if err != nil {
+		return err
+	}
+
+	if topDir != "" {
+		for _, dir := range []string{trashDir, filepath.Join(trashDir, "files"), filepath.Join(trashDir, "info")} {
+			if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+				return fmt.Errorf("Cannot create trash directory: %w", err)
+			}
+			if err := trashCheckDir(dir); err != nil {
+				return err
+			}
+		}
+	} else {
+		for _, sub := range []string{"files", "info"} {
+			if err := os.MkdirAll(filepath.Join(trashDir, sub), 0700); err != nil {
+				return fmt.Errorf("Cannot create trash directory: %w", err)
+			}
+		}
+	}
+
+	// Paths in a per-mount trash are relative to the mount's top directory
+	infoPath := abs
+	if topDir != "" {
+		infoPath, err = filepath.Rel(topDir, abs)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The .trashinfo file is created first with O_EXCL to reserve a unique name,
+	// and the file is moved with RENAME_NOREPLACE so a leftover in files/ is kept
+	base := filepath.Base(abs)
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			name = fmt.Sprintf("%s.%d", base, i)
+		}
+
+		infoFile := filepath.Join(trashDir, "info", name+".trashinfo")
+		f, err := os.OpenFile(infoFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("Cannot create '%s': %w", infoFile, err)
+		}
+
+		_, err = fmt.Fprintf(f, "[Trash Info]\nPath=%s\nDeletionDate=%s\n", (&url.URL{Path: infoPath}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(infoFile)
+			return fmt.Errorf("Cannot write '%s': %w", infoFile, err)
+		}
+
+		err = mvRenameat2(abs, filepath.Join(trashDir, "files", name), unix.RENAME_NOREPLACE)
+		if errors.Is(err, unix.EEXIST) {
+			os.Remove(infoFile)
+			continue
+		}
+		if err != nil {
+			os.Remove(infoFile)
+			return err
+		}
+		return nil
+	}
+}
+
+// Every trash directory in use: the home trash and $topdir/.Trash-$UID of
+// each mounted filesystem. Each item holds the trash and its top directory (Trash)
+func trashDirs() ([][2]string, error) {
+	home, err := trashHome()
+	if err != nil {
+		return nil, err
+	}
+	dirs := [][2]string{{home, ""}}
+
+	data, err := os.ReadFile("/proc/self/mounts")
+	if err != nil {
+		return dirs, nil
+	}
+
+	unescape := strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`)
+	trashName := ".Trash-" + strconv.Itoa(os.Getuid())
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		topDir := unescape.Replace(fields[1])
+		trashDir := filepath.Join(topDir, trashName)
+		if trashDir != home && trashCheckDir(trashDir) == nil {
+			dirs = append(dirs, [2]string{trashDir, topDir})
+		}
+	}
+	return dirs, nil
+}
+
+// Reads the .trashinfo files of a trash directory (Trash)
+func trashEntries(trashDir, topDir string) ([]trashEntry, error) {
+	infos, err := os.ReadDir(filepath.Join(trashDir, "info"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Cannot read trash '%s': %w", trashDir, err)
+	}
+
+	var entries []trashEntry
+	for _, info := range infos {
+		if !strings.HasSuffix(info.Name(), ".trashinfo") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(trashDir, "info", info.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read '%s': %w", info.Name(), err)
+		}
+
+		entry := trashEntry{name: strings.TrimSuffix(info.Name(), ".trashinfo"), trashDir: trashDir}
+		for _, line := range strings.Split(string(data), "\n") {
+			key, value, found := strings.Cut(line, "=")
+			if !found {
+				continue
+			}
+			switch key {
+			case "Path":
+				path, err := url.PathUnescape(value)
+				if err != nil {
+					path = value
+				}
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(topDir, path)
+				}
+				entry.path = path
+			case "DeletionDate":
+				entry.deleted, _ = time.ParseInLocation("2006-01-02T15:04:05", value, time.Local)
+			}
+		}
+
+		if entry.path != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// Moves a trashed file back to its original location (Trash)
+func trashRestore(entry trashEntry) error {
+	if _, err := os.Lstat(entry.path); err == nil {
+		return fmt.Errorf("Cannot restore '%s': File exists", entry.path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(entry.path), 0755); err != nil {
+		return fmt.Errorf("Error creating dirs: %w", err)
+	}
+
+	if err := Mv([]string{filepath.Join(entry.trashDir, "files", entry.name), entry.path}, false, false, true, false, false, true, "", "", "", false, nil); err != nil {
+		return err
+	}
+
+	return os.Remove(filepath.Join(entry.trashDir, "info", entry.name+".trashinfo"))
+}
+
+func Trash(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("Missing trash command: list, restore or empty")
+	}
+
+	dirs, err := trashDirs()
+	if err != nil {
+		return err
+	}
+
+	var entries []trashEntry
+	for _, dir := range dirs {
+		found, err := trashEntries(dir[0], dir[1])
+		if err != nil {
+			return err
+		}
+		entries = append(entries, found...)
+	}
+
+	// Oldest first. Names reserved later get a longer ".N" suffix, which breaks ties
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].deleted.Equal(entries[j].deleted) {
+			return entries[i].deleted.Before(entries[j].deleted)
+		}
+		if len(entries[i].name) != len(entries[j].name) {
+			return len(entries[i].name) < len(entries[j].name)
+		}
+		return entries[i].name < entries[j].name
+	})
+
+	switch args[0] {
+	case "list":
+		for _, entry := range entries {
+			fmt.Printf("%s %s\n", entry.deleted.Format("2006-01-02 15:04:05"), entry.path)
+		}
+
+	case "restore":
+		if len(args) < 2 {
+			return fmt.Errorf("Missing file operand")
+		}
+
+		for _, file := range args[1:] {
+			abs, err := filepath.Abs(file)
+			if err != nil {
+				return err
+			}
+
+			// The most recently trashed file with that path is restored
+			found := false
+			for i := len(entries) - 1; i >= 0; i-- {
+				if entries[i].path == abs {
+					if err := trashRestore(entries[i]); err != nil {
+						return err
+					}
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("Cannot restore '%s': Not found in the trash", file)
+			}
+		}
+
+	case "empty":
+		for _, dir := range dirs {
+			for _, sub := range []string{"files", "info"} {
+				items, err := os.ReadDir(filepath.Join(dir[0], sub))
+				if err != nil && !os.IsNotExist(err) {
+					return fmt.Errorf("Cannot read trash '%s': %w", dir[0], err)
+				}
+				for _, item := range items {
+					if err := rmRecursive(filepath.Join(dir[0], sub, item.Name()), "never"); err != nil {
+						return err
+					}
+				}
+			}
+			os.Remove(filepath.Join(dir[0], "directorysizes"))
+		}
+
+	default:
+		return fmt.Errorf("Unknown trash command '%s': use list, restore or empty", args[0])
+	}
+	return nil
+}
+
+func Cat(byte bool, progress func(Progress), files ...string) error {
+
+	if progress != nil && !byte {
+		total, _ := progressTotals(files)
+		pw := newProgressWriter(os.Stdout, total, len(files), progress)
+		defer pw.finish()
+
+		for _, file := range files {
+			f, err := os.Open(file)
+			if err != nil {
+				return fmt.Errorf("cannot read the file %s: %w", file, err)
+			}
+
+			pw.startFile(file)
+			_, err = io.Copy(pw, f)
+			f.Close()
+			pw.doneFile()
+			if err != nil {
+				return fmt.Errorf("cannot read the file %s: %w", file, err)
+			}
+		}
+		return nil
+	}
+
+	for _, file := range files {
+		if byte {
+			catBytePrinter(file)
+			return nil
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("cannot read the file %s: %w", file, err)
+		}
+		os.Stdout.Write(data)
+	}
+	return nil
+}
+
+func Head(lines int, files ...string) error {
+	if lines <= 0 {
+		return fmt.Errorf("Error: number of lines is 0 or negative")
+	}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return fmt.Errorf("Error opening file '%s': %w", file, err)
+		}
+
+		scanner := bufio.NewScanner(f)
+		lineCount := 0
 
 		if len(files) > 1 {
 			fmt.Printf("\n\n==> %s <==\n\n", file)
@@ -796,620 +1611,2074 @@ func isSymbolic(filePath string) (string, bool, error) {
 	}
 }
 
-// Copies source_file data to another file (Cp)
-func cpCopyFile(src, dst string, preserveAttributes bool) error {
-
-	srcFile, err := os.Open(src)
-	if err != nil {
-		return fmt.Errorf("Error opeaning '%s': %v", src, err)
-	}
-
-	destFile, err := os.Create(dst)
-	if err != nil {
-		srcFile.Close()
-		return fmt.Errorf("Error opeaning '%s': %v", dst, err)
-	}
+// This struct is used to save the options of a copy (Cp)
+type cpOptions struct {
+	interactive, force, noClobber, update, verbose bool
+	backup, suffix, sparse, reflink                string
+	umask                                          uint32
+	progress                                       *progressWriter
 
-	_, err = io.Copy(destFile, srcFile)
+	// Attributes selected with --preserve and --no-preserve
+	preserveMode, preserveOwnership, preserveTimestamps bool
+	preserveLinks, preserveXattr, preserveContext       bool
 
-	srcErr := srcFile.Close()
-	destErr := destFile.Close()
+	// First destination of every multiply linked source, by device and inode
+	links map[[2]uint64]string
+}
 
-	if preserveAttributes {
-		preserveFileAttributes(src, dst)
+// Applies a --preserve (value true) or --no-preserve (value false) attribute list (Cp)
+func cpPreserveList(list string, value bool, opts *cpOptions) error {
+	for _, attr := range strings.Split(list, ",") {
+		switch attr {
+		case "mode":
+			opts.preserveMode = value
+		case "ownership":
+			opts.preserveOwnership = value
+		case "timestamps":
+			opts.preserveTimestamps = value
+		case "links":
+			opts.preserveLinks = value
+		case "xattr":
+			opts.preserveXattr = value
+		case "context":
+			opts.preserveContext = value
+		case "all":
+			opts.preserveMode, opts.preserveOwnership, opts.preserveTimestamps = value, value, value
+			opts.preserveLinks, opts.preserveXattr, opts.preserveContext = value, value, value
+		default:
+			return fmt.Errorf("invalid argument '%s' for '--preserve'", attr)
+		}
 	}
+	return nil
+}
 
-	if err != nil {
-		return err
+// Normalize the --backup CONTROL and -S SUFFIX values. "default" selects
+// $VERSION_CONTROL, or "existing" when it is unset. An empty control means no
+// backups (Cp, Mv, Ln)
+func backupControl(control string, suffix string) (string, string, error) {
+	if control == "" && suffix != "" {
+		control = "default"
 	}
-	if srcErr != nil {
-		return srcErr
+	if control == "default" {
+		control = os.Getenv("VERSION_CONTROL")
+		if control == "" {
+			control = "existing"
+		}
 	}
-	if destErr != nil {
-		return destErr
+
+	switch control {
+	case "", "none", "off":
+		return "", "", nil
+	case "numbered", "t":
+		control = "numbered"
+	case "existing", "nil":
+		control = "existing"
+	case "simple", "never":
+		control = "simple"
+	default:
+		return "", "", fmt.Errorf("invalid argument '%s' for 'backup type'", control)
 	}
 
-	return nil
+	if suffix == "" {
+		suffix = os.Getenv("SIMPLE_BACKUP_SUFFIX")
+	}
+	if suffix == "" {
+		suffix = "~"
+	}
+	return control, suffix, nil
 }
 
-// Preserve atime, mtime and permissions of a file (Cp)
-func preserveFileAttributes(src, dst string) error {
-	info, err := os.Lstat(src)
-	if err != nil {
-		return fmt.Errorf("Error obtaining file information: %w", err)
+// Rename an existing file out of the way as GNU does: "numbered" backups are
+// file.~N~, "simple" ones file+suffix, and "existing" makes numbered backups
+// only for files that already have them. Returns the backup path, or "" when
+// there was nothing to back up (Cp, Mv, Ln)
+func backupFile(path string, control string, suffix string) (string, error) {
+	if control == "" {
+		return "", nil
 	}
-	if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
-		return err
+	if _, err := os.Lstat(path); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
 	}
-	stat, ok := info.Sys().(*syscall.Stat_t)
-	if ok {
-		atime := time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec))
-		mtime := time.Unix(int64(stat.Mtim.Sec), int64(stat.Mtim.Nsec))
-		if err := os.Chtimes(dst, atime, mtime); err != nil {
-			return fmt.Errorf("Error changing atime and mtime: %w", err)
+
+	// Find the highest existing numbered backup
+	last := 0
+	if control != "simple" {
+		entries, err := os.ReadDir(filepath.Dir(path))
+		if err != nil {
+			return "", fmt.Errorf("cannot read dir %s: %w", filepath.Dir(path), err)
 		}
-		if err := os.Chown(dst, int(stat.Uid), int(stat.Gid)); err != nil && !errors.Is(err, syscall.EPERM) {
-			return fmt.Errorf("Error changing file permisions: %w", err)
+
+		prefix := filepath.Base(path) + ".~"
+		for _, entry := range entries {
+			name := entry.Name()
+			if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, "~") || len(name) <= len(prefix)+1 {
+				continue
+			}
+			if n, err := strconv.Atoi(name[len(prefix) : len(name)-1]); err == nil && n > last {
+				last = n
+			}
 		}
 	}
-	return nil
-}
 
-func Cp(files []string, followSymbolic, recursive, dereference, nodereference, preserveAttributes bool) error {
+	backup := path + suffix
+	if control == "numbered" || (control == "existing" && last > 0) {
+		backup = fmt.Sprintf("%s.~%d~", path, last+1)
+	}
 
-	isDir, _ := isDirectory(files[len(files)-1])
+	if err := os.Rename(path, backup); err != nil {
+		return "", fmt.Errorf("Error backing up '%s': %w", path, err)
+	}
+	return backup, nil
+}
 
-	for i := range len(files) - 1 {
-		if target, isSym, err := isSymbolic(files[i]); followSymbolic && isSym {
-			if err != nil {
-				return err
+// Decides whether dst may be written, applying -n, -u, -i and --backup to an
+// existing destination. Returns false when the destination must be kept (Cp)
+func cpPrepareDest(src string, dst string, opts cpOptions) (bool, error) {
+	dstInfo, err := os.Lstat(dst)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if opts.verbose {
+				fmt.Printf("'%s' -> '%s'\n", src, dst)
 			}
-
-			files[i] = target
+			return true, nil
 		}
+		return false, fmt.Errorf("Cannot get '%s' information: %w", dst, err)
+	}
 
-		if recursive {
-			filepath.WalkDir(files[i], func(path string, d fs.DirEntry, err error) error {
-				rel, err := filepath.Rel(files[i], path)
-				if err != nil {
-					return err
-				}
+	srcInfo, err := os.Lstat(src)
+	if err != nil {
+		return false, fmt.Errorf("Cannot get '%s' information: %w", src, err)
+	}
 
-				if d.IsDir() {
-					err := os.MkdirAll(filepath.Join(files[len(files)-1], rel), 0750)
-					if err != nil {
-						return fmt.Errorf("Error creating dirs: %w", err)
-					}
+	if dstInfo.IsDir() && !srcInfo.IsDir() {
+		return false, fmt.Errorf("Cannot overwrite directory '%s' with non-directory", dst)
+	}
 
-					err = preserveFileAttributes(path, filepath.Join(files[len(files)-1], rel))
-					if err != nil {
-						return err
-					}
+	if sameInfo, err := os.Stat(dst); err == nil {
+		if realSrc, err := os.Stat(src); err == nil && os.SameFile(realSrc, sameInfo) && opts.backup == "" {
+			return false, fmt.Errorf("'%s' and '%s' are the same file", src, dst)
+		}
+	}
+
+	switch {
+	case opts.noClobber:
+		return false, nil
+	case opts.update && !dstInfo.ModTime().Before(srcInfo.ModTime()):
+		return false, nil
+	case opts.interactive && !promptYes("cp: overwrite '%s'? ", dst):
+		return false, nil
+	}
+
+	backup, err := backupFile(dst, opts.backup, opts.suffix)
+	if err != nil {
+		return false, err
+	}
+	if opts.verbose && backup != "" {
+		fmt.Printf("'%s' -> '%s' (backup: '%s')\n", src, dst, backup)
+	} else if opts.verbose {
+		fmt.Printf("'%s' -> '%s'\n", src, dst)
+	}
+	return true, nil
+}
+
+// Copies source_file data to another file (Cp)
+func cpCopyFile(src, dst string, opts cpOptions) error {
+	if ok, err := cpPrepareDest(src, dst, opts); !ok || err != nil {
+		return err
+	}
+
+	// With --preserve=links a source already copied under another name is linked to that copy
+	var linkKey [2]uint64
+	if opts.preserveLinks {
+		var stat unix.Stat_t
+		if err := unix.Stat(src, &stat); err == nil && stat.Nlink > 1 {
+			linkKey = [2]uint64{stat.Dev, stat.Ino}
+			if first, seen := opts.links[linkKey]; seen {
+				os.Remove(dst)
+				if err := os.Link(first, dst); err != nil {
+					return fmt.Errorf("Error creating hard link '%s': %w", dst, err)
+				}
+				return nil
+			}
+		}
+	}
+
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("Error opeaning '%s': %v", src, err)
+	}
+
+	srcInfo, err := srcFile.Stat()
+	if err != nil {
+		srcFile.Close()
+		return fmt.Errorf("Cannot get '%s' information: %w", src, err)
+	}
+
+	opts.progress.startFile(src)
+	defer opts.progress.doneFile()
+
+	// A new file gets the source mode minus the umask, an existing one keeps
+	// its mode. Only a file created here is removed when the copy fails
+	created := false
+	create := func() (*os.File, error) {
+		f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, srcInfo.Mode().Perm())
+		if err == nil {
+			created = true
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		return os.OpenFile(dst, os.O_WRONLY|os.O_TRUNC, 0)
+	}
+	destFile, err := create()
+	if err != nil && opts.force {
+		// -f: remove a destination that cannot be opened and try again
+		os.Remove(dst)
+		destFile, err = create()
+	}
+	if err != nil {
+		srcFile.Close()
+		return fmt.Errorf("Error opeaning '%s': %v", dst, err)
+	}
+
+	err = cpCopyData(destFile, srcFile, opts)
+
+	srcErr := srcFile.Close()
+	destErr := destFile.Close()
+
+	if err != nil {
+		if created {
+			os.Remove(dst)
+		}
+		return err
+	}
+	if srcErr != nil {
+		return srcErr
+	}
+	if destErr != nil {
+		return destErr
+	}
+
+	if linkKey != [2]uint64{} {
+		opts.links[linkKey] = dst
+	}
+
+	return preserveFileAttributes(src, dst, opts)
+}
+
+// Copies a symbolic link as a link (Cp)
+func cpSymlink(src, target, dst string, opts cpOptions) error {
+	if ok, err := cpPrepareDest(src, dst, opts); !ok || err != nil {
+		return err
+	}
+
+	if _, err := os.Lstat(dst); err == nil {
+		if err := os.Remove(dst); err != nil {
+			return fmt.Errorf("Error removing '%s': %w", dst, err)
+		}
+	}
+
+	if err := os.Symlink(target, dst); err != nil {
+		return fmt.Errorf("Error creating symbolic link: %w", err)
+	}
+	return preserveFileAttributes(src, dst, opts)
+}
+
+// Copy length bytes at off between files with copy_file_range, falling back to
+// reads and writes when the kernel cannot offload the copy (Cp)
+func cpCopyRange(dst, src *os.File, off, length int64, progress *progressWriter) error {
+	srcOff, dstOff := off, off
+	for length > 0 {
+		n, err := unix.CopyFileRange(int(src.Fd()), &srcOff, int(dst.Fd()), &dstOff, int(min(length, 1<<30)), 0)
+		if err != nil {
+			if errors.Is(err, unix.EXDEV) || errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EINVAL) || errors.Is(err, unix.EOPNOTSUPP) {
+				return cpCopyRangeRW(dst, src, srcOff, length, false, progress)
+			}
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		length -= int64(n)
+		progress.add(int64(n))
+	}
+	return nil
+}
+
+// Copy length bytes at off between files with reads and writes. With
+// skipZeros, blocks made only of zeros are not written and become holes (Cp)
+func cpCopyRangeRW(dst, src *os.File, off, length int64, skipZeros bool, progress *progressWriter) error {
+	buf := make([]byte, 128*1024)
+	for length > 0 {
+		n, err := src.ReadAt(buf[:min(int64(len(buf)), length)], off)
+		if n > 0 {
+			if !skipZeros || bytes.ContainsFunc(buf[:n], func(r rune) bool { return r != 0 }) {
+				if _, err := dst.WriteAt(buf[:n], off); err != nil {
+					return err
+				}
+			}
+			off += int64(n)
+			length -= int64(n)
+			progress.add(int64(n))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Copy only the data segments of a sparse file, found with SEEK_DATA and
+// SEEK_HOLE, so its holes stay holes (Cp)
+func cpCopySparse(dst, src *os.File, size int64, progress *progressWriter) error {
+	fd := int(src.Fd())
+	for off := int64(0); off < size; {
+		data, err := unix.Seek(fd, off, unix.SEEK_DATA)
+		if errors.Is(err, unix.ENXIO) {
+			// Only a hole is left
+			break
+		}
+		if errors.Is(err, unix.EINVAL) {
+			// The filesystem cannot report holes
+			return cpCopyRange(dst, src, off, size-off, progress)
+		}
+		if err != nil {
+			return err
+		}
+
+		hole, err := unix.Seek(fd, data, unix.SEEK_HOLE)
+		if err != nil {
+			return err
+		}
+		hole = min(hole, size)
+
+		if err := cpCopyRange(dst, src, data, hole-data, progress); err != nil {
+			return err
+		}
+		off = hole
+	}
+	return nil
+}
+
+// Copy file data. --reflink clones it with FICLONE when the filesystem
+// supports it, and --sparse selects whether holes are kept (auto), also made
+// from runs of zeros (always) or filled in (never) (Cp)
+func cpCopyData(dst, src *os.File, opts cpOptions) error {
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	if opts.reflink != "never" {
+		err := unix.IoctlFileClone(int(dst.Fd()), int(src.Fd()))
+		if err == nil {
+			opts.progress.add(info.Size())
+			return nil
+		}
+		if opts.reflink == "always" {
+			return fmt.Errorf("Failed to clone '%s' from '%s': %w", dst.Name(), src.Name(), err)
+		}
+	}
+
+	// Offsets and truncation only work between regular files, anything else
+	// (FIFOs, devices, sockets) is streamed
+	dstInfo, err := dst.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() || !dstInfo.Mode().IsRegular() {
+		_, err := io.Copy(dst, io.TeeReader(src, opts.progress))
+		return err
+	}
+
+	// procfs and sysfs files report 0 or a page as their size whatever
+	// their content, so a file that ends before its size is streamed too
+	size := info.Size()
+	if size > 0 {
+		if _, err := src.ReadAt(make([]byte, 1), size-1); err == io.EOF {
+			_, err := io.Copy(dst, io.TeeReader(src, opts.progress))
+			return err
+		}
+	}
+	switch opts.sparse {
+	case "always":
+		err = cpCopyRangeRW(dst, src, 0, size, true, opts.progress)
+	case "never":
+		err = cpCopyRangeRW(dst, src, 0, size, false, opts.progress)
+	default:
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if ok && stat.Blocks*512 < size {
+			err = cpCopySparse(dst, src, size, opts.progress)
+		} else {
+			err = cpCopyRange(dst, src, 0, size, opts.progress)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// Trailing holes are not written, so set the size explicitly
+	if err := dst.Truncate(size); err != nil {
+		return err
+	}
+
+	// Copy whatever lies past the size, from a file that grew during the
+	// copy or one that reports a size of 0
+	if _, err := src.Seek(size, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := dst.Seek(size, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, io.TeeReader(src, opts.progress))
+	return err
+}
+
+// Copy extended attributes: ACLs go with mode, security.selinux with context
+// and every other attribute with xattr (Cp)
+func cpCopyXattrs(src, dst string, opts cpOptions) error {
+	size, err := unix.Llistxattr(src, nil)
+	if err != nil || size == 0 {
+		if err != nil && !errors.Is(err, unix.ENOTSUP) {
+			return fmt.Errorf("Error listing extended attributes of '%s': %w", src, err)
+		}
+		return nil
+	}
+
+	buf := make([]byte, size)
+	size, err = unix.Llistxattr(src, buf)
+	if err != nil {
+		return fmt.Errorf("Error listing extended attributes of '%s': %w", src, err)
+	}
+
+	for _, name := range strings.Split(string(buf[:size]), "\x00") {
+		var wanted bool
+		switch {
+		case name == "":
+			continue
+		case strings.HasPrefix(name, "system.posix_acl_"):
+			wanted = opts.preserveMode || opts.preserveXattr
+		case name == "security.selinux":
+			wanted = opts.preserveContext
+		default:
+			wanted = opts.preserveXattr
+		}
+		if !wanted {
+			continue
+		}
+
+		valueSize, err := unix.Lgetxattr(src, name, nil)
+		if err != nil {
+			return fmt.Errorf("Error reading '%s' of '%s': %w", name, src, err)
+		}
+		value := make([]byte, valueSize)
+		valueSize, err = unix.Lgetxattr(src, name, value)
+		if err != nil {
+			return fmt.Errorf("Error reading '%s' of '%s': %w", name, src, err)
+		}
+
+		err = unix.Lsetxattr(dst, name, value[:valueSize], 0)
+		if err != nil && !errors.Is(err, unix.ENOTSUP) && !errors.Is(err, unix.EPERM) {
+			return fmt.Errorf("Error preserving '%s' of '%s': %w", name, dst, err)
+		}
+	}
+	return nil
+}
+
+// Preserve the attributes selected with --preserve on dst. Symbolic links
+// only keep their ownership, extended attributes and timestamps (Cp)
+func preserveFileAttributes(src, dst string, opts cpOptions) error {
+	if !opts.preserveMode && !opts.preserveOwnership && !opts.preserveTimestamps && !opts.preserveXattr && !opts.preserveContext {
+		return nil
+	}
+
+	var stat unix.Stat_t
+	if err := unix.Lstat(src, &stat); err != nil {
+		return fmt.Errorf("Error obtaining file information: %w", err)
+	}
+	isLink := stat.Mode&unix.S_IFMT == unix.S_IFLNK
+
+	// A dereferenced symbolic link gives the attributes of what it points to
+	if info, err := os.Lstat(dst); isLink && err == nil && info.Mode()&os.ModeSymlink == 0 {
+		if err := unix.Stat(src, &stat); err != nil {
+			return fmt.Errorf("Error obtaining file information: %w", err)
+		}
+		isLink = false
+	}
+
+	// Ownership goes first, as changing it may clear the setuid and setgid bits
+	if opts.preserveOwnership {
+		if err := os.Lchown(dst, int(stat.Uid), int(stat.Gid)); err != nil && !errors.Is(err, syscall.EPERM) {
+			return fmt.Errorf("Error changing file ownership: %w", err)
+		}
+	}
+
+	if opts.preserveMode && !isLink {
+		if err := unix.Chmod(dst, stat.Mode&07777); err != nil {
+			return fmt.Errorf("Error changing file permisions: %w", err)
+		}
+	}
+
+	if opts.preserveMode || opts.preserveXattr || opts.preserveContext {
+		if err := cpCopyXattrs(src, dst, opts); err != nil {
+			return err
+		}
+	}
+
+	if opts.preserveTimestamps {
+		times := []unix.Timespec{stat.Atim, stat.Mtim}
+		if err := unix.UtimesNanoAt(unix.AT_FDCWD, dst, times, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+			return fmt.Errorf("Error changing atime and mtime: %w", err)
+		}
+	}
+	return nil
+}
+
+// Copy a non-recursive operand: symbolic links are followed unless -P is given
+// and directories are skipped (Cp)
+func cpOperand(src, dst string, nodereference bool, opts cpOptions) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return fmt.Errorf("Cannot get '%s' information: %w", src, err)
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		if nodereference {
+			target, err := os.Readlink(src)
+			if err != nil {
+				return fmt.Errorf("Error reading symlink target: %w", err)
+			}
+			return cpSymlink(src, target, dst, opts)
+		}
+
+		info, err = os.Stat(src)
+		if err != nil {
+			return fmt.Errorf("Cannot get '%s' information: %w", src, err)
+		}
+	}
+
+	if info.IsDir() {
+		return fmt.Errorf("-r not specified; omitting directory '%s'", src)
+	}
+
+	return cpCopyFile(src, dst, opts)
+}
+
+// Split the operands into sources and a target, which is the -t DIRECTORY or
+// the last operand. isDir reports whether the sources go inside the target;
+// it is stat'ed once for all of them (Cp, Mv, Ln)
+func targetOperands(files []string, noTargetDir bool, targetDir string) (string, []string, bool, error) {
+	var target string
+	var sources []string
+	if targetDir != "" {
+		target, sources = targetDir, files
+	} else if len(files) > 0 {
+		target, sources = files[len(files)-1], files[:len(files)-1]
+	}
+
+	if len(sources) == 0 {
+		return "", nil, false, fmt.Errorf("Missing file operand")
+	}
+
+	isDir, _ := isDirectory(target)
+	switch {
+	case noTargetDir && targetDir != "":
+		return "", nil, false, fmt.Errorf("Cannot combine --target-directory (-t) and --no-target-directory (-T)")
+	case noTargetDir && len(sources) > 1:
+		return "", nil, false, fmt.Errorf("Extra operand '%s'", target)
+	case noTargetDir:
+		isDir = false
+	case targetDir != "" && !isDir:
+		return "", nil, false, fmt.Errorf("Target '%s' is not a directory", target)
+	case len(sources) > 1 && !isDir:
+		return "", nil, false, fmt.Errorf("Target '%s' is not a directory", target)
+	}
+	return target, sources, isDir, nil
+}
+
+// Check if dst is the directory src or lies inside it (Cp, Mv)
+func isInside(dst, src string) bool {
+	realSrc, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return false
+	}
+
+	// dst may not exist yet, its parent has to
+	realDst, err := filepath.EvalSymlinks(filepath.Dir(dst))
+	if err != nil {
+		return false
+	}
+	realDst = filepath.Join(realDst, filepath.Base(dst))
+
+	rel, err := filepath.Rel(realSrc, realDst)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// Recreate a FIFO, device node or socket (Cp)
+func cpSpecial(src, dst string, stat *unix.Stat_t, opts cpOptions) error {
+	if ok, err := cpPrepareDest(src, dst, opts); !ok || err != nil {
+		return err
+	}
+
+	if _, err := os.Lstat(dst); err == nil {
+		if err := os.Remove(dst); err != nil {
+			return fmt.Errorf("Error removing '%s': %w", dst, err)
+		}
+	}
+
+	if err := unix.Mknod(dst, stat.Mode, int(stat.Rdev)); err != nil {
+		return fmt.Errorf("Error creating special file '%s': %w", dst, err)
+	}
+	return preserveFileAttributes(src, dst, opts)
+}
+
+// Copy src to dst recursively. follow says whether src itself is followed if
+// it is a symbolic link, dereference does the same for everything below it.
+// ancestors holds the directories being copied, by device and inode, so
+// symbolic link loops are detected (Cp)
+func cpTree(src, dst string, follow bool, dereference bool, ancestors map[[2]uint64]bool, opts cpOptions) error {
+	var stat unix.Stat_t
+	var err error
+	if follow {
+		err = unix.Stat(src, &stat)
+	} else {
+		err = unix.Lstat(src, &stat)
+	}
+	if err != nil {
+		return fmt.Errorf("Cannot get '%s' information: %w", src, err)
+	}
+
+	switch stat.Mode & unix.S_IFMT {
+	case unix.S_IFDIR:
+		if isInside(dst, src) {
+			return fmt.Errorf("Cannot copy a directory, '%s', into itself, '%s'", src, dst)
+		}
+		return cpDir(src, dst, &stat, dereference, ancestors, opts)
+
+	case unix.S_IFLNK:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return fmt.Errorf("Error reading symlink target: %w", err)
+		}
+		return cpSymlink(src, target, dst, opts)
+
+	case unix.S_IFREG:
+		return cpCopyFile(src, dst, opts)
+
+	default:
+		return cpSpecial(src, dst, &stat, opts)
+	}
+}
+
+// Copy a directory and its contents. Its mode and attributes are set once
+// the contents are copied, so a read-only directory can still be filled and
+// copying into it cannot clobber its timestamps (Cp)
+func cpDir(src, dst string, stat *unix.Stat_t, dereference bool, ancestors map[[2]uint64]bool, opts cpOptions) error {
+	key := [2]uint64{stat.Dev, stat.Ino}
+	if ancestors[key] {
+		return fmt.Errorf("Filesystem loop detected: '%s' leads back to one of its parent directories", src)
+	}
+	ancestors[key] = true
+	defer delete(ancestors, key)
+
+	created := false
+	dstInfo, err := os.Stat(dst)
+	switch {
+	case err == nil && !dstInfo.IsDir():
+		return fmt.Errorf("Cannot overwrite non-directory '%s' with directory '%s'", dst, src)
+	case err == nil:
+	case os.IsNotExist(err):
+		if err := unix.Mkdir(dst, stat.Mode&07777|0700); err != nil {
+			return fmt.Errorf("Error creating dir '%s': %w", dst, err)
+		}
+		created = true
+		if opts.verbose {
+			fmt.Printf("'%s' -> '%s'\n", src, dst)
+		}
+	default:
+		return fmt.Errorf("Cannot get '%s' information: %w", dst, err)
+	}
+
+	var errs []error
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("cannot read dir %s: %w", src, err))
+	}
+
+	for _, entry := range entries {
+		err := cpTree(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name()), dereference, dereference, ancestors, opts)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if created && !opts.preserveMode {
+		if err := unix.Chmod(dst, stat.Mode&07777&^opts.umask); err != nil {
+			errs = append(errs, fmt.Errorf("Error changing '%s' mode: %w", dst, err))
+		}
+	}
+
+	if err := preserveFileAttributes(src, dst, opts); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func Cp(files []string, followSymbolic, recursive, dereference, nodereference bool, preserve string, noPreserve string, interactive, force, noClobber, update, verbose, archive, noTargetDir bool, targetDir string, backup string, suffix string, sparse string, reflink string, progress func(Progress)) error {
+	if archive {
+		recursive, nodereference = true, true
+		preserve = "all"
+	}
+
+	backup, suffix, err := backupControl(backup, suffix)
+	if err != nil {
+		return err
+	}
+
+	switch sparse {
+	case "auto", "always", "never":
+	default:
+		return fmt.Errorf("invalid argument '%s' for '--sparse'", sparse)
+	}
+	switch reflink {
+	case "auto", "always", "never":
+	default:
+		return fmt.Errorf("invalid argument '%s' for '--reflink'", reflink)
+	}
+
+	opts := cpOptions{
+		interactive: interactive && !noClobber,
+		force:       force,
+		noClobber:   noClobber,
+		update:      update,
+		verbose:     verbose,
+		backup:      backup,
+		suffix:      suffix,
+		sparse:      sparse,
+		reflink:     reflink,
+		umask:       uint32(getUmask()),
+		links:       make(map[[2]uint64]string),
+	}
+
+	if preserve != "" {
+		if err := cpPreserveList(preserve, true, &opts); err != nil {
+			return err
+		}
+	}
+	if noPreserve != "" {
+		if err := cpPreserveList(noPreserve, false, &opts); err != nil {
+			return err
+		}
+	}
+
+	target, sources, isDir, err := targetOperands(files, noTargetDir, targetDir)
+	if err != nil {
+		return err
+	}
+
+	if progress != nil {
+		total, totalFiles := progressTotals(sources)
+		opts.progress = newProgressWriter(nil, total, totalFiles, progress)
+		defer opts.progress.finish()
+	}
+
+	// Keep going after a failed operand and report every error at the end
+	var errs []error
+	for _, src := range sources {
+		dst := target
+		if isDir {
+			dst = filepath.Join(target, filepath.Base(src))
+		}
+
+		var err error
+		if recursive {
+			// -H and -L follow a symbolic link named on the command line
+			err = cpTree(src, dst, followSymbolic || dereference, dereference, make(map[[2]uint64]bool), opts)
+		} else {
+			err = cpOperand(src, dst, nodereference, opts)
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// getMonthLines is a helper function that prints the calendar for a specific month and year.
+func getMonthLines(year int, month time.Month) []string {
+	var lines []string
+
+	monthHeader := fmt.Sprintf("%s %d", month.String(), year)
+	centeredHeader := fmt.Sprintf("%*s", -10-len(monthHeader)/2, fmt.Sprintf("%*s", 10+len(monthHeader)/2, monthHeader))
+	lines = append(lines, centeredHeader)
+
+	lines = append(lines, "Su Mo Tu We Th Fr Sa")
+
+	firstOfMonth := time.Date(year, month, 1, 0, 0, 0, 0, time.Local)
+	lastDayOfMonth := time.Date(year, month+1, 0, 0, 0, 0, 0, time.Local)
+
+	var currentLine strings.Builder
+	currentLine.WriteString(strings.Repeat("   ", int(firstOfMonth.Weekday())))
+
+	for day := 1; day <= lastDayOfMonth.Day(); day++ {
+		currentLine.WriteString(fmt.Sprintf("%2d ", day))
+
+		if (day+int(firstOfMonth.Weekday()))%7 == 0 {
+			lines = append(lines, strings.TrimRight(currentLine.String(), " "))
+			currentLine.Reset()
+		}
+	}
+
+	if currentLine.Len() > 0 {
+		lines = append(lines, strings.TrimRight(currentLine.String(), " "))
+	}
+
+	return lines
+}
+
+func Cal(date []string) {
+	if len(date) == 1 {
+		year, err := strconv.Atoi(date[0])
+		if err != nil {
+			year = time.Now().Year()
+		}
+
+		fmt.Printf("%27s\n", strconv.Itoa(year))
+
+		for row := 0; row < 4; row++ {
+			month1 := time.Month(row*3 + 1)
+			month2 := time.Month(row*3 + 2)
+			month3 := time.Month(row*3 + 3)
+
+			lines1 := getMonthLines(year, month1)
+			lines2 := getMonthLines(year, month2)
+			lines3 := getMonthLines(year, month3)
+
+			fmt.Printf("%-22s  %-22s  %-22s\n", lines1[0], lines2[0], lines3[0])
+			fmt.Printf("%-22s  %-22s  %-22s\n", lines1[1], lines2[1], lines3[1])
+
+			maxLines := len(lines1)
+			if len(lines2) > maxLines {
+				maxLines = len(lines2)
+			}
+			if len(lines3) > maxLines {
+				maxLines = len(lines3)
+			}
+
+			for i := 2; i < maxLines; i++ {
+				line1, line2, line3 := "", "", ""
+				if i < len(lines1) {
+					line1 = lines1[i]
+				}
+				if i < len(lines2) {
+					line2 = lines2[i]
+				}
+				if i < len(lines3) {
+					line3 = lines3[i]
+				}
+				fmt.Printf("%-22s  %-22s  %-22s\n", line1, line2, line3)
+			}
+		}
+		return
+	}
 
-				} else {
-					target, isSym, err := isSymbolic(path)
-					if err != nil {
-						return err
-					}
+	month := int(time.Now().Month())
+	year := time.Now().Year()
+	var err error
 
-					if nodereference && isSym {
-						err := os.Symlink(target, filepath.Join(files[len(files)-1], rel))
-						if err != nil {
-							return fmt.Errorf("Error creating symbolic link: %w", err)
-						}
-						return nil
-					}
+	if len(date) >= 1 {
+		month, err = strconv.Atoi(date[0])
+		if err != nil || month < 1 || month > 12 {
+			month = int(time.Now().Month())
+		}
+	}
+	if len(date) >= 2 {
+		year, err = strconv.Atoi(date[1])
+		if err != nil {
+			year = time.Now().Year()
+		}
+	}
 
-					if dereference && isSym {
-						path = target
-					}
+	lines := getMonthLines(year, time.Month(month))
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
 
-					err = cpCopyFile(path, filepath.Join(files[len(files)-1], rel), preserveAttributes)
-					if err != nil {
-						return err
-					}
+// Parse a byte count with an optional kB, K, MB, M, GB or G suffix (Cmp)
+func cmpNumber(s string) (int64, error) {
+	multipliers := []struct {
+		suffix string
+		value  int64
+	}{{"kB", 1000}, {"K", 1 << 10}, {"MB", 1000 * 1000}, {"M", 1 << 20}, {"GB", 1000 * 1000 * 1000}, {"G", 1 << 30}}
+
+	mult := int64(1)
+	for _, m := range multipliers {
+		if strings.HasSuffix(s, m.suffix) {
+			s, mult = strings.TrimSuffix(s, m.suffix), m.value
+			break
+		}
+	}
 
-				}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid number '%s'", s)
+	}
+	return n * mult, nil
+}
 
-				return nil
-			})
+// Show a byte as cat -v does, for -b (Cmp)
+func cmpPrintable(c byte) string {
+	var s string
+	if c >= 128 {
+		s, c = "M-", c-128
+	}
+	switch {
+	case c < 32:
+		return s + "^" + string(rune(c+64))
+	case c == 127:
+		return s + "^?"
+	}
+	return s + string(rune(c))
+}
 
-			return nil
+// Open a cmp operand and skip its first bytes. "-" is standard input (Cmp)
+func cmpOpen(file string, skip int64) (*os.File, error) {
+	f := os.Stdin
+	if file != "-" {
+		var err error
+		if f, err = os.Open(file); err != nil {
+			return nil, fmt.Errorf("Error opening file '%s': %w", file, err)
 		}
+	}
 
-		if isDir {
-			err := cpCopyFile(files[i], filepath.Join(files[len(files)-1], filepath.Base(files[i])), preserveAttributes)
-			if err != nil {
-				return err
+	// Pipes cannot seek, their bytes are read and dropped
+	if skip > 0 {
+		if _, err := f.Seek(skip, io.SeekCurrent); err != nil {
+			if _, err := io.CopyN(io.Discard, f, skip); err != nil && err != io.EOF {
+				f.Close()
+				return nil, fmt.Errorf("Error skipping bytes of '%s': %w", file, err)
 			}
-		} else {
+		}
+	}
+	return f, nil
+}
 
-			target, isSym, err := isSymbolic(files[i])
-			if err != nil {
-				return err
+func Cmp(file1 string, file2 string, verbose bool, quiet bool, printBytes bool, limit string, skip string) (bool, int, error) {
+	if verbose && quiet {
+		return false, 2, fmt.Errorf("Options -l and -s are incompatible")
+	}
+
+	// -i SKIP1[:SKIP2] skips the same number of bytes in both files unless SKIP2 is given
+	var skip1, skip2 int64
+	if skip != "" {
+		first, second, found := strings.Cut(skip, ":")
+		var err error
+		if skip1, err = cmpNumber(first); err != nil {
+			return false, 2, fmt.Errorf("Invalid --ignore-initial value: %w", err)
+		}
+		skip2 = skip1
+		if found {
+			if skip2, err = cmpNumber(second); err != nil {
+				return false, 2, fmt.Errorf("Invalid --ignore-initial value: %w", err)
 			}
+		}
+	}
 
-			if nodereference && isSym {
-				err := os.Symlink(target, files[len(files)-1])
-				if err != nil {
-					return fmt.Errorf("Error creating symbolic link: %w", err)
+	remaining := int64(-1)
+	if limit != "" {
+		n, err := cmpNumber(limit)
+		if err != nil {
+			return false, 2, fmt.Errorf("Invalid --bytes value: %w", err)
+		}
+		remaining = n
+	}
+
+	f1, err := cmpOpen(file1, skip1)
+	if err != nil {
+		return false, 2, err
+	}
+	defer f1.Close()
+
+	f2, err := cmpOpen(file2, skip2)
+	if err != nil {
+		return false, 2, err
+	}
+	defer f2.Close()
+
+	buf1 := make([]byte, 64*1024)
+	buf2 := make([]byte, 64*1024)
+	var offset int64
+	line := int64(1)
+	differ := false
+
+	for remaining != 0 {
+		want := len(buf1)
+		if remaining > 0 && remaining < int64(want) {
+			want = int(remaining)
+		}
+
+		n1, err1 := io.ReadFull(f1, buf1[:want])
+		n2, err2 := io.ReadFull(f2, buf2[:want])
+		if err1 != nil && err1 != io.EOF && err1 != io.ErrUnexpectedEOF {
+			return false, 2, fmt.Errorf("Error reading '%s': %w", file1, err1)
+		}
+		if err2 != nil && err2 != io.EOF && err2 != io.ErrUnexpectedEOF {
+			return false, 2, fmt.Errorf("Error reading '%s': %w", file2, err2)
+		}
+
+		n := min(n1, n2)
+		if !bytes.Equal(buf1[:n], buf2[:n]) {
+			for i := 0; i < n; i++ {
+				c1, c2 := buf1[i], buf2[i]
+				if c1 == c2 {
+					continue
+				}
+
+				differ = true
+				pos := offset + int64(i) + 1
+				switch {
+				case quiet:
+					return false, 1, nil
+				case verbose && printBytes:
+					fmt.Printf("%d %o %-4s %o %s\n", pos, c1, cmpPrintable(c1), c2, cmpPrintable(c2))
+				case verbose:
+					fmt.Printf("%d %o %o\n", pos, c1, c2)
+				case printBytes:
+					line += int64(bytes.Count(buf1[:i], []byte{'\n'}))
+					fmt.Printf("%s %s differ: byte %d, line %d is %o %s %o %s\n", file1, file2, pos, line, c1, cmpPrintable(c1), c2, cmpPrintable(c2))
+					return false, 1, nil
+				default:
+					line += int64(bytes.Count(buf1[:i], []byte{'\n'}))
+					fmt.Printf("%s %s differ: char %d, line %d\n", file1, file2, pos, line)
+					return false, 1, nil
 				}
-				return nil
 			}
+		}
 
-			err = cpCopyFile(files[i], files[len(files)-1], preserveAttributes)
-			if err != nil {
-				return err
+		line += int64(bytes.Count(buf1[:n], []byte{'\n'}))
+		offset += int64(n)
+		if remaining > 0 {
+			remaining -= int64(n)
+		}
+
+		// One file ended before the other
+		if n1 != n2 {
+			if !quiet {
+				shorter := file1
+				if n2 < n1 {
+					shorter = file2
+				}
+				switch {
+				case offset == 0:
+					fmt.Fprintf(os.Stderr, "cmp: EOF on %s which is empty\n", shorter)
+				case verbose:
+					fmt.Fprintf(os.Stderr, "cmp: EOF on %s after byte %d\n", shorter, offset)
+				default:
+					fmt.Fprintf(os.Stderr, "cmp: EOF on %s after byte %d, in line %d\n", shorter, offset, line)
+				}
 			}
+			return false, 1, nil
+		}
+		if n1 < want {
+			break
+		}
+	}
+
+	if differ {
+		return false, 1, nil
+	}
+	return true, 0, nil
+}
+
+// Rename with rename(2), or renameat2(2) when flags are given. RENAME_NOREPLACE
+// falls back to a check followed by rename(2) on kernels and filesystems that
+// do not support it, RENAME_EXCHANGE has no fallback (Mv)
+func mvRenameat2(src, dst string, flags uint) error {
+	// Plain rename(2) replaces an empty directory, which os.Rename refuses to do
+	if flags == 0 {
+		if err := unix.Rename(src, dst); err != nil {
+			return &os.LinkError{Op: "rename", Old: src, New: dst, Err: err}
+		}
+		return nil
+	}
+
+	err := unix.Renameat2(unix.AT_FDCWD, src, unix.AT_FDCWD, dst, flags)
+	if err == nil {
+		return nil
+	}
+
+	unsupported := errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EINVAL)
+	if unsupported && flags == unix.RENAME_NOREPLACE {
+		if _, err := os.Lstat(dst); err == nil {
+			return &os.LinkError{Op: "rename", Old: src, New: dst, Err: unix.EEXIST}
+		}
+		return os.Rename(src, dst)
+	}
+	if unsupported && flags == unix.RENAME_EXCHANGE {
+		return fmt.Errorf("atomic exchange is not supported by this kernel or filesystem")
+	}
+	return &os.LinkError{Op: "renameat2", Old: src, New: dst, Err: err}
+}
+
+// Move one file, reporting it to the progress writer. flags are renameat2(2)
+// flags. Moves across filesystems fall back to copying, except exchanges (Mv)
+func mvRename(src, dst string, flags uint, progress *progressWriter) error {
+	var size int64
+	var count int
+	if progress != nil {
+		size, count = progressTotals([]string{src})
+		progress.startFile(src)
+	}
+
+	err := mvRenameat2(src, dst, flags)
+	if errors.Is(err, unix.EXDEV) && flags&unix.RENAME_EXCHANGE != 0 {
+		return fmt.Errorf("cannot exchange files on different filesystems")
+	}
+	if errors.Is(err, unix.EXDEV) {
+		return mvCrossDevice(src, dst, flags, progress)
+	}
+	if err != nil {
+		return err
+	}
+
+	progress.skip(size, count)
+	return nil
+}
+
+// Move a file to another filesystem with the cp engine, preserving every
+// attribute. The copy is made under a temporary name next to dst and renamed
+// into place, so dst never shows a partial copy, and removed if the copy
+// fails. The source is removed last (Mv)
+func mvCrossDevice(src, dst string, flags uint, progress *progressWriter) error {
+	tmp := filepath.Join(filepath.Dir(dst), fmt.Sprintf(".%s.mv-%d-%d", filepath.Base(dst), os.Getpid(), time.Now().UnixNano()))
+
+	opts := cpOptions{
+		sparse:   "auto",
+		reflink:  "auto",
+		umask:    uint32(getUmask()),
+		progress: progress,
+		links:    make(map[[2]uint64]string),
+	}
+	cpPreserveList("all", true, &opts)
+
+	if err := cpTree(src, tmp, false, false, make(map[[2]uint64]bool), opts); err != nil {
+		rmRecursive(tmp, "never")
+		return err
+	}
+
+	if err := mvRenameat2(tmp, dst, flags); err != nil {
+		rmRecursive(tmp, "never")
+		return err
+	}
+
+	if err := rmRecursive(src, "never"); err != nil {
+		return fmt.Errorf("Error removing '%s' after copying it: %w", src, err)
+	}
+	return nil
+}
+
+// This struct is used to save the mv options (Mv)
+type mvOptions struct {
+	interactive, force, noClobber, update, verbose, exchange bool
+	backup, suffix                                           string
+	progress                                                 *progressWriter
+}
+
+// Move a single source to dst, which already includes the source basename
+// when the target is a directory (Mv)
+func mvFile(src, dst string, opts mvOptions) error {
+	srcInfo, err := os.Lstat(src)
+	if err != nil {
+		return fmt.Errorf("Cannot stat '%s': %w", src, err)
+	}
+
+	dstInfo, err := os.Lstat(dst)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Cannot get '%s' information: %w", dst, err)
+	}
+	exists := err == nil
+
+	if exists && os.SameFile(srcInfo, dstInfo) {
+		return fmt.Errorf("'%s' and '%s' are the same file", src, dst)
+	}
+	if srcInfo.IsDir() && isInside(dst, src) {
+		return fmt.Errorf("Cannot move '%s' to a subdirectory of itself, '%s'", src, dst)
+	}
+
+	if opts.exchange {
+		if err := mvRename(src, dst, unix.RENAME_EXCHANGE, opts.progress); err != nil {
+			return fmt.Errorf("Cannot exchange '%s' and '%s': %w", src, dst, err)
+		}
+		if opts.verbose {
+			fmt.Printf("exchanged '%s' <-> '%s'\n", src, dst)
+		}
+		return nil
+	}
+
+	var backup string
+	if exists {
+		switch {
+		case dstInfo.IsDir() && !srcInfo.IsDir():
+			return fmt.Errorf("Cannot overwrite directory '%s' with non-directory", dst)
+		case !dstInfo.IsDir() && srcInfo.IsDir():
+			return fmt.Errorf("Cannot overwrite non-directory '%s' with directory '%s'", dst, src)
+		case opts.noClobber:
+			return nil
+		case opts.update && !dstInfo.ModTime().Before(srcInfo.ModTime()):
+			return nil
+		}
+
+		// POSIX also asks before replacing a write-protected file when stdin is a terminal
+		prompt := opts.interactive
+		if !opts.force && !opts.interactive && dstInfo.Mode()&os.ModeSymlink == 0 && unix.Access(dst, unix.W_OK) != nil && isTerminal(os.Stdin) {
+			prompt = true
+		}
+		if prompt && !opts.force && !mvPrompt(dst) {
+			return nil
+		}
+
+		if backup, err = backupFile(dst, opts.backup, opts.suffix); err != nil {
+			return err
+		}
+	}
+
+	// -n is enforced by the kernel, so a file created since the check above is not replaced
+	var flags uint
+	if opts.noClobber {
+		flags = unix.RENAME_NOREPLACE
+	}
+	if err := mvRename(src, dst, flags, opts.progress); err != nil {
+		if opts.noClobber && errors.Is(err, unix.EEXIST) {
+			return nil
 		}
+		return fmt.Errorf("Cannot move '%s' to '%s': %w", src, dst, err)
+	}
 
+	if opts.verbose && backup != "" {
+		fmt.Printf("renamed '%s' -> '%s' (backup: '%s')\n", src, dst, backup)
+	} else if opts.verbose {
+		fmt.Printf("renamed '%s' -> '%s'\n", src, dst)
 	}
 	return nil
 }
 
-// getMonthLines is a helper function that prints the calendar for a specific month and year.
-func getMonthLines(year int, month time.Month) []string {
-	var lines []string
+func Mv(files []string, interactive, force, noClobber, update, verbose, noTargetDir bool, targetDir string, backup string, suffix string, exchange bool, progress func(Progress)) error {
+	if noClobber && backup != "" {
+		return fmt.Errorf("Options --backup and --no-clobber are mutually exclusive")
+	}
+	if exchange && (noClobber || backup != "") {
+		return fmt.Errorf("Option --exchange cannot be combined with --backup or --no-clobber")
+	}
 
-	monthHeader := fmt.Sprintf("%s %d", month.String(), year)
-	centeredHeader := fmt.Sprintf("%*s", -10-len(monthHeader)/2, fmt.Sprintf("%*s", 10+len(monthHeader)/2, monthHeader))
-	lines = append(lines, centeredHeader)
+	// Exchanging two operands swaps them even when the target is a directory
+	if exchange && targetDir == "" && len(files) == 2 {
+		noTargetDir = true
+	}
 
-	lines = append(lines, "Su Mo Tu We Th Fr Sa")
+	backup, suffix, err := backupControl(backup, suffix)
+	if err != nil {
+		return err
+	}
 
-	firstOfMonth := time.Date(year, month, 1, 0, 0, 0, 0, time.Local)
-	lastDayOfMonth := time.Date(year, month+1, 0, 0, 0, 0, 0, time.Local)
+	target, sources, isDir, err := targetOperands(files, noTargetDir, targetDir)
+	if err != nil {
+		return err
+	}
 
-	var currentLine strings.Builder
-	currentLine.WriteString(strings.Repeat("   ", int(firstOfMonth.Weekday())))
+	// -f and -n both override -i
+	opts := mvOptions{
+		interactive: interactive && !noClobber,
+		force:       force,
+		noClobber:   noClobber,
+		update:      update,
+		verbose:     verbose,
+		exchange:    exchange,
+		backup:      backup,
+		suffix:      suffix,
+	}
 
-	for day := 1; day <= lastDayOfMonth.Day(); day++ {
-		currentLine.WriteString(fmt.Sprintf("%2d ", day))
+	if progress != nil {
+		total, totalFiles := progressTotals(sources)
+		opts.progress = newProgressWriter(nil, total, totalFiles, progress)
+		defer opts.progress.finish()
+	}
 
-		if (day+int(firstOfMonth.Weekday()))%7 == 0 {
-			lines = append(lines, strings.TrimRight(currentLine.String(), " "))
-			currentLine.Reset()
+	var errs []error
+	for _, src := range sources {
+		dst := target
+		if isDir {
+			dst = filepath.Join(target, filepath.Base(src))
+		}
+		if err := mvFile(src, dst, opts); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// This struct is used to save one tee output (Tee)
+type teeOutput struct {
+	name string
+	w    io.Writer
+}
+
+func Tee(source io.Reader, files []string, appendFlag bool, ignoreInterrupts bool, outputError string) error {
+	switch outputError {
+	case "", "warn", "warn-nopipe", "exit", "exit-nopipe":
+	default:
+		return fmt.Errorf("invalid argument '%s' for '--output-error'", outputError)
+	}
+
+	if ignoreInterrupts {
+		signal.Ignore(os.Interrupt)
+	}
+	// With --output-error a closed pipe is reported as EPIPE instead of killing tee
+	if outputError != "" {
+		signal.Ignore(unix.SIGPIPE)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendFlag {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+
+	var errs []error
+	var opened []*os.File
+	outputs := []teeOutput{{"standard output", os.Stdout}}
+	for _, file := range files {
+		f, err := os.OpenFile(file, flags, 0644)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Error opening file '%s': %w", file, err))
+			continue
+		}
+		opened = append(opened, f)
+		outputs = append(outputs, teeOutput{file, f})
+	}
+
+	// Every chunk is written as soon as it is read so pipelines stay interactive.
+	// An output that fails is dropped and the others keep receiving data
+	buf := make([]byte, 32*1024)
+	for len(outputs) > 0 {
+		n, readErr := source.Read(buf)
+		if n > 0 {
+			kept := outputs[:0]
+			for _, out := range outputs {
+				_, err := out.w.Write(buf[:n])
+				if err == nil {
+					kept = append(kept, out)
+					continue
+				}
+
+				pipe := errors.Is(err, unix.EPIPE)
+				switch {
+				case pipe && (outputError == "warn-nopipe" || outputError == "exit-nopipe"):
+				case outputError == "exit" || outputError == "exit-nopipe" || (pipe && outputError == ""):
+					errs = append(errs, fmt.Errorf("Error writing to '%s': %w", out.name, err))
+					for _, f := range opened {
+						f.Close()
+					}
+					return errors.Join(errs...)
+				default:
+					errs = append(errs, fmt.Errorf("Error writing to '%s': %w", out.name, err))
+				}
+			}
+			outputs = kept
+		}
+
+		if readErr == io.EOF {
+			break
 		}
+		if readErr != nil {
+			errs = append(errs, fmt.Errorf("Error reading standard input: %w", readErr))
+			break
+		}
+	}
+
+	for _, f := range opened {
+		if err := f.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("Error closing '%s': %w", f.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// This struct is used to save the ln options (Ln)
+type lnOptions struct {
+	symbolic, force, logical, relative, interactive, verbose bool
+	backup, suffix                                           string
+}
+
+// Compute the text of a symlink at dst pointing to src relative to the
+// directory of dst. Both directories are resolved first so that symlinked
+// parents do not break the result (Ln)
+func lnRelative(src, dst string) (string, error) {
+	resolve := func(path string) (string, error) {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return "", err
+		}
+		if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+			return resolved, nil
+		}
+		return abs, nil
+	}
+
+	srcDir, err := resolve(filepath.Dir(src))
+	if err != nil {
+		return "", err
 	}
-
-	if currentLine.Len() > 0 {
-		lines = append(lines, strings.TrimRight(currentLine.String(), " "))
+	dstDir, err := resolve(filepath.Dir(dst))
+	if err != nil {
+		return "", err
 	}
 
-	return lines
+	return filepath.Rel(dstDir, filepath.Join(srcDir, filepath.Base(src)))
 }
 
-func Cal(date []string) {
-	if len(date) == 1 {
-		year, err := strconv.Atoi(date[0])
+// Create one link at dst, replacing or backing up an existing file as asked (Ln)
+func lnFile(src, dst string, opts lnOptions) error {
+	if !opts.symbolic {
+		srcInfo, err := os.Lstat(src)
 		if err != nil {
-			year = time.Now().Year()
+			return fmt.Errorf("Failed to access '%s': %w", src, err)
 		}
+		if srcInfo.IsDir() {
+			return fmt.Errorf("'%s': hard link not allowed for directory", src)
+		}
+	}
 
-		fmt.Printf("%27s\n", strconv.Itoa(year))
-
-		for row := 0; row < 4; row++ {
-			month1 := time.Month(row*3 + 1)
-			month2 := time.Month(row*3 + 2)
-			month3 := time.Month(row*3 + 3)
-
-			lines1 := getMonthLines(year, month1)
-			lines2 := getMonthLines(year, month2)
-			lines3 := getMonthLines(year, month3)
-
-			fmt.Printf("%-22s  %-22s  %-22s\n", lines1[0], lines2[0], lines3[0])
-			fmt.Printf("%-22s  %-22s  %-22s\n", lines1[1], lines2[1], lines3[1])
+	var backup string
+	if dstInfo, err := os.Lstat(dst); err == nil {
+		if dstInfo.IsDir() {
+			return fmt.Errorf("Cannot overwrite directory '%s'", dst)
+		}
+		if srcInfo, err := os.Stat(src); err == nil && !opts.symbolic && os.SameFile(srcInfo, dstInfo) && opts.backup == "" {
+			return fmt.Errorf("'%s' and '%s' are the same file", src, dst)
+		}
 
-			maxLines := len(lines1)
-			if len(lines2) > maxLines {
-				maxLines = len(lines2)
+		switch {
+		case opts.interactive && !promptYes("ln: replace '%s'? ", dst):
+			return nil
+		case opts.backup != "":
+			if backup, err = backupFile(dst, opts.backup, opts.suffix); err != nil {
+				return err
 			}
-			if len(lines3) > maxLines {
-				maxLines = len(lines3)
+		case opts.force || opts.interactive:
+			if err := os.Remove(dst); err != nil {
+				return fmt.Errorf("Cannot remove '%s': %w", dst, err)
 			}
+		default:
+			return fmt.Errorf("Failed to create link '%s': File exists", dst)
+		}
+	}
 
-			for i := 2; i < maxLines; i++ {
-				line1, line2, line3 := "", "", ""
-				if i < len(lines1) {
-					line1 = lines1[i]
-				}
-				if i < len(lines2) {
-					line2 = lines2[i]
-				}
-				if i < len(lines3) {
-					line3 = lines3[i]
-				}
-				fmt.Printf("%-22s  %-22s  %-22s\n", line1, line2, line3)
+	arrow := "=>"
+	if opts.symbolic {
+		arrow = "->"
+		text := src
+		if opts.relative {
+			var err error
+			if text, err = lnRelative(src, dst); err != nil {
+				return fmt.Errorf("Cannot compute relative path for '%s': %w", src, err)
 			}
 		}
-		return
+		if err := os.Symlink(text, dst); err != nil {
+			return fmt.Errorf("Failed to create symbolic link '%s': %w", dst, err)
+		}
+		src = text
+	} else {
+		// -L links to what a symlink points to, -P to the symlink itself
+		flags := 0
+		if opts.logical {
+			flags = unix.AT_SYMLINK_FOLLOW
+		}
+		if err := unix.Linkat(unix.AT_FDCWD, src, unix.AT_FDCWD, dst, flags); err != nil {
+			return fmt.Errorf("Failed to create hard link '%s' => '%s': %w", dst, src, err)
+		}
 	}
 
-	month := int(time.Now().Month())
-	year := time.Now().Year()
-	var err error
+	if opts.verbose && backup != "" {
+		fmt.Printf("'%s' %s '%s' (backup: '%s')\n", dst, arrow, src, backup)
+	} else if opts.verbose {
+		fmt.Printf("'%s' %s '%s'\n", dst, arrow, src)
+	}
+	return nil
+}
 
-	if len(date) >= 1 {
-		month, err = strconv.Atoi(date[0])
-		if err != nil || month < 1 || month > 12 {
-			month = int(time.Now().Month())
-		}
+func Ln(files []string, symbolic, force, logical, physical, relative, noDereference, interactive, verbose, noTargetDir bool, targetDir string, backup string, suffix string) error {
+	if relative && !symbolic {
+		return fmt.Errorf("Cannot do --relative without --symbolic")
 	}
-	if len(date) >= 2 {
-		year, err = strconv.Atoi(date[1])
-		if err != nil {
-			year = time.Now().Year()
-		}
+	if logical && physical {
+		return fmt.Errorf("Options -L and -P are mutually exclusive")
 	}
 
-	lines := getMonthLines(year, time.Month(month))
-	for _, line := range lines {
-		fmt.Println(line)
+	backup, suffix, err := backupControl(backup, suffix)
+	if err != nil {
+		return err
 	}
-}
 
-func Cmp(file1 string, file2 string, verbose bool, quiet bool) (bool, int, error) {
+	// A single operand is linked into the current directory
+	if len(files) == 1 && targetDir == "" && !noTargetDir {
+		files = append(files, ".")
+	}
 
-	f1, err := os.Open(file1)
+	target, sources, isDir, err := targetOperands(files, noTargetDir, targetDir)
 	if err != nil {
+		return err
+	}
 
-		return false, 2, fmt.Errorf("Error opening file '%s': %w", file1, err)
+	// With -n a symlink to a directory is replaced, not entered
+	if isDir && noDereference && targetDir == "" {
+		if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			isDir = false
+		}
+	}
 
+	opts := lnOptions{
+		symbolic:    symbolic,
+		force:       force,
+		logical:     logical,
+		relative:    relative,
+		interactive: interactive && !force,
+		verbose:     verbose,
+		backup:      backup,
+		suffix:      suffix,
 	}
-	defer f1.Close()
 
-	f2, err := os.Open(file2)
-	if err != nil {
-		return false, 2, fmt.Errorf("Error opening file '%s': %w", file2, err)
+	var errs []error
+	for _, src := range sources {
+		dst := target
+		if isDir {
+			dst = filepath.Join(target, filepath.Base(src))
+		}
+		if err := lnFile(src, dst, opts); err != nil {
+			errs = append(errs, err)
+		}
 	}
-	defer f2.Close()
 
-	b1 := make([]byte, 1)
-	b2 := make([]byte, 1)
-	newLine := 1
+	return errors.Join(errs...)
+}
 
-	for i := 1; ; i++ {
+// Read one line ending in delim, without the delimiter. A last line without
+// a delimiter is still returned (Comm)
+func commReadLine(r *bufio.Reader, delim byte) (string, bool, error) {
+	line, err := r.ReadString(delim)
+	if err == io.EOF {
+		return line, line != "", nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return line[:len(line)-1], true, nil
+}
 
-		_, err1 := f1.Read(b1)
-		_, err2 := f2.Read(b2)
+func Comm(file1 string, file2 string, noCol1 bool, noCol2 bool, noCol3 bool, checkOrder bool, noCheckOrder bool, delimiter string, zeroTerminated bool, total bool) error {
+	names := [2]string{file1, file2}
+	var readers [2]*bufio.Reader
+	for i, name := range names {
+		if name == "-" {
+			readers[i] = bufio.NewReader(os.Stdin)
+			continue
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("Error opening file '%s': %w", name, err)
+		}
+		defer f.Close()
+		readers[i] = bufio.NewReader(f)
+	}
 
-		if err1 != nil || err2 != nil {
-			switch {
-			case err1 == io.EOF && err2 == io.EOF:
-				return true, 0, nil
+	eol := byte('\n')
+	if zeroTerminated {
+		eol = 0
+	}
 
-			case err1 == io.EOF:
-				if !quiet {
-					fmt.Printf("cmp: EOF on %s after byte %d\n", file1, i)
-				}
-				return false, 1, nil
+	// Column 2 is preceded by one delimiter and column 3 by two, minus the suppressed columns
+	prefix2, prefix3 := "", ""
+	if !noCol1 {
+		prefix2, prefix3 = delimiter, delimiter
+	}
+	if !noCol2 {
+		prefix3 += delimiter
+	}
 
-			case err2 == io.EOF:
-				if !quiet {
-					fmt.Printf("cmp: EOF on %s after byte %d\n", file2, i)
-				}
-				return false, 1, nil
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
 
-			case err1 != nil:
-				return false, 2, fmt.Errorf("error reading %s: %w\n", file1, err1)
+	compare := localeCompare()
+	var lines [2]string
+	var has, disordered [2]bool
+	var counts [3]int
+	seenUnpairable := false
 
-			case err2 != nil:
-				return false, 2, fmt.Errorf("error reading %s: %w", file2, err2)
-			}
+	// By default the order is only checked once a line could not be paired,
+	// --check-order checks every line and stops at the first unsorted one
+	next := func(i int) error {
+		line, ok, err := commReadLine(readers[i], eol)
+		if err != nil {
+			return fmt.Errorf("Error reading '%s': %w", names[i], err)
 		}
 
-		if !bytes.Equal(b1, b2) {
-			if !verbose {
-				if !quiet {
-					fmt.Printf("%s %s differ: byte %d line %d\n", file1, file2, i, newLine)
-				}
-				return false, 1, nil
+		check := checkOrder || (!noCheckOrder && seenUnpairable)
+		if check && ok && has[i] && !disordered[i] && compare(line, lines[i]) < 0 {
+			disordered[i] = true
+			if checkOrder {
+				return fmt.Errorf("File %d is not in sorted order", i+1)
 			}
-			fmt.Printf("%d %o %o\n", i, b1, b2)
 		}
 
-		if bytes.Equal(b1, []byte{'\n'}) && !verbose {
-			newLine++
-		}
+		lines[i], has[i] = line, ok
+		return nil
+	}
 
+	for i := range readers {
+		if err := next(i); err != nil {
+			return err
+		}
 	}
 
-}
+	for has[0] || has[1] {
+		var err error
+		order := 0
+		if has[0] && has[1] {
+			order = compare(lines[0], lines[1])
+		}
 
-func Mv(files []string, interactive bool, force bool) error {
-	if len(files) == 2 {
-		info, err := os.Stat(files[1])
-		if err != nil {
-			if os.IsNotExist(err) {
-				err := os.Rename(files[0], files[1])
-				if err != nil {
-					return fmt.Errorf("Error renaming file: %v", err)
-				}
-				return nil
-			} else {
-				return fmt.Errorf("Error: %v\n", err)
+		switch {
+		case !has[1] || (has[0] && order < 0):
+			seenUnpairable = true
+			counts[0]++
+			if !noCol1 {
+				w.WriteString(lines[0])
+				w.WriteByte(eol)
 			}
-		} else {
-			if info.IsDir() {
-				targetPath := filepath.Join(files[1], files[0])
-				err := os.Rename(files[0], targetPath)
-				if err != nil {
-					return fmt.Errorf("Error moving file: %v", err)
-				}
-				return nil
+			err = next(0)
+		case !has[0] || order > 0:
+			seenUnpairable = true
+			counts[1]++
+			if !noCol2 {
+				w.WriteString(prefix2 + lines[1])
+				w.WriteByte(eol)
 			}
-		}
-		if interactive && !force {
-			if asw := mvPrompt(files[1]); !asw {
-				return nil
+			err = next(1)
+		default:
+			counts[2]++
+			if !noCol3 {
+				w.WriteString(prefix3 + lines[0])
+				w.WriteByte(eol)
+			}
+			if err = next(0); err == nil {
+				err = next(1)
 			}
 		}
-		err = os.Rename(files[0], files[1])
+
 		if err != nil {
-			return fmt.Errorf("Error renaming file: %v", err)
+			return err
 		}
-		return nil
 	}
 
-	for i := range len(files) - 1 {
-		info, err := os.Stat(files[len(files)-1])
-		if err != nil {
-			if os.IsNotExist(err) || !info.IsDir() {
-				return fmt.Errorf("Dir does not exist: %v", err)
-			} else {
-				return fmt.Errorf("Error: %v\n", err)
-			}
-		}
+	if total {
+		fmt.Fprintf(w, "%d%s%d%s%d%stotal%c", counts[0], delimiter, counts[1], delimiter, counts[2], delimiter, eol)
+	}
 
-		if interactive && fileExists(filepath.Join(files[len(files)-1], files[i])) && !force {
-			if asw := mvPrompt(files[i]); !asw {
-				continue
-			}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("Error writing output: %w", err)
+	}
 
+	var errs []error
+	for i := range disordered {
+		if disordered[i] {
+			errs = append(errs, fmt.Errorf("File %d is not in sorted order", i+1))
 		}
+	}
+	return errors.Join(errs...)
+}
 
-		err = os.Rename(files[i], filepath.Join(files[len(files)-1], files[i]))
-		if err != nil {
-			return fmt.Errorf("Error moving file: %v", err)
+// Format mode bits like the permission part of ls -l, e.g. rwsr-xr-t (Chmod)
+func modeString(mode uint32) string {
+	b := []byte("rwxrwxrwx")
+	for i := range b {
+		if mode&(1<<(8-i)) == 0 {
+			b[i] = '-'
 		}
 	}
 
-	return nil
+	special := []struct {
+		bit  uint32
+		idx  int
+		char byte
+	}{{04000, 2, 's'}, {02000, 5, 's'}, {01000, 8, 't'}}
+	for _, sp := range special {
+		if mode&sp.bit == 0 {
+			continue
+		}
+		if b[sp.idx] == 'x' {
+			b[sp.idx] = sp.char
+		} else {
+			b[sp.idx] = sp.char - 'a' + 'A'
+		}
+	}
+	return string(b)
 }
 
-func Tee(source io.Reader, files []string, appendFlag bool, ignoreInterrupts bool) error {
-	destinations := []io.Writer{os.Stdout}
-
-	if ignoreInterrupts {
-		signal.Ignore(os.Interrupt)
+// Change the mode of one file. With follow a symbolic link is followed,
+// without it a symbolic link is skipped: the file is opened with O_PATH and
+// O_NOFOLLOW and changed through that descriptor, so a link swapped in after
+// the check is never chmod'ed through (Chmod)
+func chmodFile(path string, mode string, umask uint32, verbose bool, changes bool, follow bool) error {
+	flags := unix.O_PATH | unix.O_CLOEXEC
+	if !follow {
+		flags |= unix.O_NOFOLLOW
 	}
-	for _, file := range files {
-		var f *os.File
-		var err error
-		if appendFlag {
-			f, err = os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		} else {
-			f, err = os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-		}
-		defer f.Close()
+	fd, err := unix.Open(path, flags, 0)
+	if err != nil {
+		return fmt.Errorf("Cannot access '%s': %w", path, err)
+	}
+	defer unix.Close(fd)
 
-		if err != nil {
-			return fmt.Errorf("Error opening file '%s': %w", file, err)
-		}
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		return fmt.Errorf("Cannot access '%s': %w", path, err)
+	}
+	if stat.Mode&unix.S_IFMT == unix.S_IFLNK {
+		return nil
+	}
 
-		destinations = append(destinations, f)
+	oldMode := stat.Mode & 07777
+	newMode, err := fileMode.Parse(mode, oldMode, stat.Mode&unix.S_IFMT == unix.S_IFDIR, umask)
+	if err != nil {
+		return err
+	}
 
+	// fchmod does not take O_PATH descriptors, their /proc entry does
+	if err := unix.Chmod(fmt.Sprintf("/proc/self/fd/%d", fd), newMode); err != nil {
+		return fmt.Errorf("Error changing '%s' mode: %w", path, err)
 	}
-	multiWriter := io.MultiWriter(destinations...)
 
-	if _, err := io.Copy(multiWriter, source); err != nil {
-		return fmt.Errorf("Error copying data to files: %v", err)
+	switch {
+	case oldMode != newMode && (verbose || changes):
+		fmt.Printf("mode of '%s' changed from %04o (%s) to %04o (%s)\n", path, oldMode, modeString(oldMode), newMode, modeString(newMode))
+	case verbose:
+		fmt.Printf("mode of '%s' retained as %04o (%s)\n", path, oldMode, modeString(oldMode))
 	}
 	return nil
 }
 
-func Ln(files []string, symbolic bool, force bool, logical bool, physical bool) error {
-	var err error
-	isDir, _ := isDirectory(files[len(files)-1])
+// Change files mode recursively. Only the root is followed if it is a symbolic
+// link, links found while walking are skipped. A file that cannot be changed
+// is reported, unless quiet, and sets failed; the walk goes on (Chmod)
+func chmodRecursive(root string, mode string, umask uint32, verbose bool, changes bool, quiet bool, failed *bool) fs.WalkDirFunc {
+	return func(path string, info fs.DirEntry, err error) error {
+		if err != nil {
+			if !quiet {
+				log.Printf("Error accessing path %s: %v", path, err)
+			}
+			*failed = true
+			return nil
+		}
 
-	if force {
-		if isDir {
-			for i := range len(files) - 1 {
-				err = lnForce(filepath.Join(files[len(files)-1], files[i]))
+		// A command line link is followed, and named without the slash added to walk it
+		follow := path == root
+		if follow && len(path) > 1 {
+			path = strings.TrimSuffix(path, "/")
+		}
+
+		if err := chmodFile(path, mode, umask, verbose, changes, follow); err != nil {
+			if !quiet {
+				log.Print(err)
 			}
-		} else {
-			err = lnForce(files[1])
+			*failed = true
 		}
+		return nil
 	}
+}
 
-	if err != nil {
+func Chmod(mode string, files []string, recursive bool, reference string, verbose bool, changes bool, quiet bool, preserveRoot bool) error {
+	if reference != "" {
+		var stat unix.Stat_t
+		if err := unix.Stat(reference, &stat); err != nil {
+			return fmt.Errorf("Cannot get '%s' information: %w", reference, err)
+		}
+		mode = strconv.FormatUint(uint64(stat.Mode&07777), 8)
+	}
+
+	// Catch an invalid mode once, before touching any file
+	if _, err := fileMode.Parse(mode, 0, false, 0); err != nil {
 		return err
 	}
 
-	for i := range len(files) - 1 {
+	umask := uint32(getUmask())
+	rootInfo, rootErr := os.Stat("/")
 
-		if symbolic {
-			if isDir {
-				err = os.Symlink(files[i], filepath.Join(files[len(files)-1], files[i]))
-			} else {
-				err = os.Symlink(files[0], files[1])
+	// Every operand is processed even when an earlier one failed
+	var errs []error
+	failed := false
+	for _, file := range files {
+		if recursive {
+			if info, statErr := os.Stat(file); preserveRoot && statErr == nil && rootErr == nil && os.SameFile(info, rootInfo) {
+				return fmt.Errorf("It is dangerous to operate recursively on '%s' (same as '/'), use --no-preserve-root to override", file)
 			}
-		} else if logical {
-			if isDir {
-				finalFile, errEval := filepath.EvalSymlinks(files[i])
-				if errEval != nil {
-					return fmt.Errorf("Error dereferencing link: %w", errEval)
-				}
-				err = os.Link(finalFile, filepath.Join(files[len(files)-1], files[i]))
-			} else {
-				finalFile, errEval := filepath.EvalSymlinks(files[0])
-				if errEval != nil {
-					return fmt.Errorf("Error dereferencing link: %w", errEval)
+
+			// filepath.WalkDir does not descend through a symbolic link root,
+			// a trailing slash makes it resolve a command line link
+			root := file
+			if info, err := os.Lstat(file); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+				if isDir, _ := isDirectory(file); isDir {
+					root = strings.TrimSuffix(file, "/") + "/"
 				}
-				err = os.Link(finalFile, files[1])
-			}
-		} else if physical {
-			if isDir {
-				err = os.Link(files[i], filepath.Join(files[len(files)-1], files[i]))
-			} else {
-				err = os.Link(files[0], files[1])
-			}
-		} else {
-			if isDir {
-				err = os.Link(files[i], filepath.Join(files[len(files)-1], files[i]))
-			} else {
-				err = os.Link(files[0], files[1])
 			}
+			filepath.WalkDir(root, chmodRecursive(root, mode, umask, verbose, changes, quiet, &failed))
+		} else if err := chmodFile(file, mode, umask, verbose, changes, true); err != nil && !quiet {
+			errs = append(errs, err)
 		}
+	}
+
+	if failed && !quiet {
+		errs = append(errs, fmt.Errorf("Cannot change the mode of some files"))
+	}
+	return errors.Join(errs...)
+}
+
+// This struct is used to save the requested ownership change (Chown, Chgrp)
+type chownSpec struct {
+	uid, gid, fromUid, fromGid  int
+	verbose, changes, groupOnly bool
+}
 
+// Resolve a user name, falling back to a numeric ID (Chown)
+func chownLookupUser(name string) (int, error) {
+	if userInfo, err := osUser.Lookup(name); err == nil {
+		uid, err := strconv.Atoi(userInfo.Uid)
 		if err != nil {
-			return fmt.Errorf("Error creating link: %v", err)
+			return -1, fmt.Errorf("Error converting Uid '%s' to int: %w", userInfo.Uid, err)
 		}
+		return uid, nil
+	}
 
+	uid, err := strconv.Atoi(name)
+	if err != nil || uid < 0 {
+		return -1, fmt.Errorf("Cannot find user '%s'", name)
 	}
-	return nil
+	return uid, nil
 }
 
-func Comm(file1 string, file2 string, noCol1 bool, noCol2 bool, noCol3 bool) error {
-	f1, err := os.Open(file1)
-	if err != nil {
-		return fmt.Errorf("Error opening file '%s': %w", file1, err)
+// Resolve a group name, falling back to a numeric ID (Chown)
+func chownLookupGroup(name string) (int, error) {
+	if groupInfo, err := osUser.LookupGroup(name); err == nil {
+		gid, err := strconv.Atoi(groupInfo.Gid)
+		if err != nil {
+			return -1, fmt.Errorf("Error converting Gid '%s' to int: %w", groupInfo.Gid, err)
+		}
+		return gid, nil
 	}
 
-	f2, err := os.Open(file2)
-	if err != nil {
-		return fmt.Errorf("Error opening file '%s': %w", file2, err)
+	gid, err := strconv.Atoi(name)
+	if err != nil || gid < 0 {
+		return -1, fmt.Errorf("Cannot find group '%s'", name)
 	}
+	return gid, nil
+}
 
-	scan1 := bufio.NewScanner(f1)
-	scan2 := bufio.NewScanner(f2)
-
-	hasLine1 := scan1.Scan()
-	hasLine2 := scan2.Scan()
+// Parse an owner[:group] operand. The group may also follow a dot, and
+// "owner:" selects the owner's login group. Omitted IDs are -1 (Chown)
+func chownParseOwner(spec string) (int, int, error) {
+	uid, gid := -1, -1
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 5, ' ', 0)
-	fmt.Fprintf(w, "%s\t%s\tBoth\n", file1, file2)
-	for hasLine1 || hasLine2 {
-		line1, line2 := scan1.Text(), scan2.Text()
-		switch {
-		case !hasLine2 || (hasLine1 && line1 < line2):
-			if !noCol1 {
-				fmt.Fprintf(w, "%s\t\n", line1)
-			}
-			hasLine1 = scan1.Scan()
-		case !hasLine1 || (hasLine2 && line2 < line1):
-			if !noCol2 {
-				fmt.Fprintf(w, "\t%s\t\n", line2)
-			}
-			hasLine2 = scan2.Scan()
-		case hasLine2 && hasLine1 && line1 == line2:
-			if !noCol3 {
-				fmt.Fprintf(w, "\t\t%s\t\n", line1)
-			}
-			hasLine1 = scan1.Scan()
-			hasLine2 = scan2.Scan()
+	userStr, groupStr, hasGroup := strings.Cut(spec, ":")
+	if !hasGroup && strings.Contains(spec, ".") {
+		if _, err := chownLookupUser(spec); err != nil {
+			userStr, groupStr, hasGroup = strings.Cut(spec, ".")
+		}
+	}
 
+	var err error
+	if userStr != "" {
+		uid, err = chownLookupUser(userStr)
+		if err != nil {
+			return -1, -1, err
 		}
 	}
 
-	w.Flush()
+	if groupStr != "" {
+		gid, err = chownLookupGroup(groupStr)
+		if err != nil {
+			return -1, -1, err
+		}
+	} else if hasGroup && userStr != "" {
+		userInfo, err := osUser.LookupId(strconv.Itoa(uid))
+		if err != nil {
+			return -1, -1, fmt.Errorf("Cannot find the login group of '%s': %w", userStr, err)
+		}
+		gid, err = strconv.Atoi(userInfo.Gid)
+		if err != nil {
+			return -1, -1, fmt.Errorf("Error converting Gid '%s' to int: %w", userInfo.Gid, err)
+		}
+	}
+	return uid, gid, nil
+}
 
-	if err := scan1.Err(); err != nil {
-		return fmt.Errorf("Error scanning %s: %w", file1, err)
+// Format an owner and group as names, or numbers when they have no name.
+// With groupOnly only the group is formatted (Chown, Chgrp)
+func chownNames(uid uint32, gid uint32, groupOnly bool) string {
+	group := strconv.Itoa(int(gid))
+	if g, err := osUser.LookupGroupId(group); err == nil {
+		group = g.Name
 	}
-	if err := scan2.Err(); err != nil {
-		return fmt.Errorf("Error scanning %s: %w", file2, err)
+	if groupOnly {
+		return group
 	}
 
-	return nil
+	owner := strconv.Itoa(int(uid))
+	if u, err := osUser.LookupId(owner); err == nil {
+		owner = u.Username
+	}
+	return owner + ":" + group
 }
 
-// Change files ownership recursively (Chown)
-func chownRecursive(physical bool, logical bool, uid int, gid int) fs.WalkDirFunc {
-	switch {
-	case physical || (!physical && !logical):
-		return func(path string, info fs.DirEntry, err error) error {
-			if err != nil {
-				log.Printf("Error accessing path %s: %v", path, err)
-				return nil
-			}
+// Change the ownership of one file, or of the file it points to when follow
+// is set. Files not owned by spec.fromUid/fromGid are left alone (Chown, Chgrp)
+func chownFile(path string, follow bool, spec chownSpec) error {
+	what := "ownership"
+	if spec.groupOnly {
+		what = "group"
+	}
 
-			if err := os.Lchown(path, uid, gid); err != nil {
-				return fmt.Errorf("Error: Ownership cannot be changed '%s': %w", path, err)
-			}
-			return nil
+	var stat unix.Stat_t
+	var err error
+	if follow {
+		err = unix.Stat(path, &stat)
+	} else {
+		err = unix.Lstat(path, &stat)
+	}
+	if err != nil {
+		return fmt.Errorf("Cannot access '%s': %w", path, err)
+	}
+
+	if (spec.fromUid != -1 && int(stat.Uid) != spec.fromUid) || (spec.fromGid != -1 && int(stat.Gid) != spec.fromGid) {
+		if spec.verbose {
+			fmt.Printf("%s of '%s' retained as %s\n", what, path, chownNames(stat.Uid, stat.Gid, spec.groupOnly))
 		}
+		return nil
+	}
+
+	if follow {
+		err = os.Chown(path, spec.uid, spec.gid)
+	} else {
+		err = os.Lchown(path, spec.uid, spec.gid)
+	}
+	if err != nil {
+		return fmt.Errorf("Error: Ownership cannot be changed '%s': %w", path, err)
+	}
 
-	case logical:
-		return func(path string, info fs.DirEntry, err error) error {
-			if err != nil {
-				log.Printf("Error accessing path %s: %v", path, err)
-				return nil
-			}
+	newUid, newGid := stat.Uid, stat.Gid
+	if spec.uid != -1 {
+		newUid = uint32(spec.uid)
+	}
+	if spec.gid != -1 {
+		newGid = uint32(spec.gid)
+	}
 
-			err = os.Chown(path, uid, gid)
-			if err != nil {
-				return fmt.Errorf("Error: Ownership cannot be changed '%s': %w", path, err)
-			}
-			return nil
-		}
+	oldNames, newNames := chownNames(stat.Uid, stat.Gid, spec.groupOnly), chownNames(newUid, newGid, spec.groupOnly)
+	switch {
+	case oldNames != newNames && (spec.verbose || spec.changes):
+		fmt.Printf("changed %s of '%s' from %s to %s\n", what, path, oldNames, newNames)
+	case spec.verbose:
+		fmt.Printf("%s of '%s' retained as %s\n", what, path, newNames)
 	}
 	return nil
 }
 
-func Chown(ug string, files []string, noDereference bool, recursive bool, physical bool, logical bool, hybrid bool) error {
-
-	userId := -1
-	groupId := -1
+// Walk a file tree like filepath.WalkDir, but descend into symbolic links to
+// directories. Directories already visited are skipped to avoid cycles (Chown, Chgrp)
+func walkLogical(root string, fn fs.WalkDirFunc) error {
+	visited := make(map[[2]uint64]bool)
 
-	if num := strings.Index(ug, ":"); num != -1 {
-		userStr := ug[:num]
-		groupStr := ug[num+1:]
-
-		if userStr != "" {
-			userInfo, err := osUser.Lookup(userStr)
-			if err != nil {
-				return fmt.Errorf("Cannot find user '%s': %w", userStr, err)
-			}
+	var walk func(path string) error
+	walk = func(path string) error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fn(path, nil, err)
+		}
 
-			uid, err := strconv.Atoi(userInfo.Uid)
-			if err != nil {
-				return fmt.Errorf("Error converting the Uid '%s' to int: %w", userInfo.Uid, err)
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok && info.IsDir() {
+			key := [2]uint64{stat.Dev, stat.Ino}
+			if visited[key] {
+				return nil
 			}
-			userId = uid
+			visited[key] = true
 		}
 
-		if groupStr != "" {
-			groupInfo, err := osUser.LookupGroup(groupStr)
-			if err != nil {
-				return fmt.Errorf("Cannot find group '%s': %w", groupStr, err)
+		if err := fn(path, fs.FileInfoToDirEntry(info), nil); err != nil {
+			if err == filepath.SkipDir {
+				return nil
 			}
+			return err
+		}
 
-			gid, err := strconv.Atoi(groupInfo.Gid)
-			if err != nil {
-				return fmt.Errorf("Error converting Gid '%s' to int: %w", groupInfo.Gid, err)
-			}
-			groupId = gid
+		if !info.IsDir() {
+			return nil
 		}
 
-	} else {
-		userInfo, err := osUser.Lookup(ug)
+		entries, err := os.ReadDir(path)
 		if err != nil {
-			return fmt.Errorf("Cannot find user '%s': %w", ug, err)
+			return fn(path, nil, err)
 		}
-		uid, err := strconv.Atoi(userInfo.Uid)
+		for _, entry := range entries {
+			if err := walk(filepath.Join(path, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(root)
+}
+
+// Change files ownership recursively (Chown, Chgrp)
+func chownRecursive(physical bool, logical bool, spec chownSpec) fs.WalkDirFunc {
+	return func(path string, info fs.DirEntry, err error) error {
 		if err != nil {
-			return fmt.Errorf("Error converting Uid '%s' to int: %w", userInfo.Uid, err)
+			log.Printf("Error accessing path %s: %v", path, err)
+			return nil
 		}
-		userId = uid
+
+		// -L changes what symbolic links point to, -P (the default) the links themselves
+		return chownFile(path, logical && !physical, spec)
 	}
+}
+
+// Apply an ownership change to each file, walking directories with -R as the
+// last of the -H, -L and -P options selects: traversal is "H", "L", or "P"
+// and "" for the default -P (Chown, Chgrp)
+func chownFiles(files []string, noDereference bool, recursive bool, traversal string, preserveRoot bool, spec chownSpec) error {
+	var err error
+	rootInfo, rootErr := os.Stat("/")
 
 	for _, file := range files {
 		if recursive {
-			if hybrid {
-				fileInfo, err := os.Lstat(file)
-				if err != nil {
-					return fmt.Errorf("Error: %w", err)
+			if info, statErr := os.Stat(file); preserveRoot && statErr == nil && rootErr == nil && os.SameFile(info, rootInfo) {
+				return fmt.Errorf("It is dangerous to operate recursively on '%s' (same as '/'), use --no-preserve-root to override", file)
+			}
+
+			switch traversal {
+			case "L":
+				err = walkLogical(file, chownRecursive(false, true, spec))
+
+			case "H":
+				// Only a symbolic link named on the command line is traversed
+				fileInfo, lstatErr := os.Lstat(file)
+				if lstatErr != nil {
+					return fmt.Errorf("Error: %w", lstatErr)
 				}
 
 				if fileInfo.Mode()&os.ModeSymlink == os.ModeSymlink {
@@ -1418,28 +3687,69 @@ func Chown(ug string, files []string, noDereference bool, recursive bool, physic
 						return fmt.Errorf("Error solving link: %w", err)
 					}
 				}
-				walkFunc := chownRecursive(true, false, userId, groupId)
-				filepath.WalkDir(file, walkFunc)
+				err = filepath.WalkDir(file, chownRecursive(true, false, spec))
 
-			} else {
-				walkFunc := chownRecursive(physical, logical, userId, groupId)
-				filepath.WalkDir(file, walkFunc)
-			}
-		} else if noDereference {
-			err := os.Lchown(file, userId, groupId)
-			if err != nil {
-				return fmt.Errorf("Error changing %s ownership: %w", file, err)
+			default:
+				err = filepath.WalkDir(file, chownRecursive(true, false, spec))
 			}
 		} else {
-			err := os.Chown(file, userId, groupId)
-			if err != nil {
-				return fmt.Errorf("Error changing %s ownership: %w", file, err)
-			}
+			err = chownFile(file, !noDereference, spec)
+		}
+
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+func Chown(ug string, files []string, noDereference bool, recursive bool, traversal string, from string, reference string, verbose bool, changes bool, preserveRoot bool) error {
+	spec := chownSpec{uid: -1, gid: -1, fromUid: -1, fromGid: -1, verbose: verbose, changes: changes}
+
+	var err error
+	if reference != "" {
+		var stat unix.Stat_t
+		if err := unix.Stat(reference, &stat); err != nil {
+			return fmt.Errorf("Cannot get '%s' information: %w", reference, err)
+		}
+		spec.uid, spec.gid = int(stat.Uid), int(stat.Gid)
+	} else {
+		spec.uid, spec.gid, err = chownParseOwner(ug)
+		if err != nil {
+			return err
+		}
+	}
+
+	if from != "" {
+		spec.fromUid, spec.fromGid, err = chownParseOwner(from)
+		if err != nil {
+			return err
+		}
+	}
+
+	return chownFiles(files, noDereference, recursive, traversal, preserveRoot, spec)
+}
+
+func Chgrp(group string, files []string, noDereference bool, recursive bool, traversal string, reference string, verbose bool, changes bool, preserveRoot bool) error {
+	spec := chownSpec{uid: -1, gid: -1, fromUid: -1, fromGid: -1, verbose: verbose, changes: changes, groupOnly: true}
+
+	if reference != "" {
+		var stat unix.Stat_t
+		if err := unix.Stat(reference, &stat); err != nil {
+			return fmt.Errorf("Cannot get '%s' information: %w", reference, err)
+		}
+		spec.gid = int(stat.Gid)
+	} else {
+		gid, err := chownLookupGroup(group)
+		if err != nil {
+			return err
+		}
+		spec.gid = gid
+	}
+
+	return chownFiles(files, noDereference, recursive, traversal, preserveRoot, spec)
+}
+
 // Touch timestamp layout (Touch)
 func getTouchTLayout(timeString string) (string, error) {
 	hasDot := strings.Contains(timeString, ".")
@@ -1549,198 +3859,458 @@ func Touch(files []string, noCreate bool, accessOnly bool, modifyOnly bool, date
 	return nil
 }
 
-func Uniq(input string, output string, duplicated bool, unique bool, counter bool, fields uint, chars uint) error {
-	lineCounts := make(map[string]int)
-	var outputFileBool bool = false
-	var textBytes []byte
-	f, err := os.Open(input)
-	if err != nil {
-		return fmt.Errorf("Error opening file '%s': %w", input, err)
+// Return the part of line that is compared: the first fields fields, each
+// made of blanks followed by non-blanks, are skipped and then chars
+// characters. At most width characters are kept when width is not negative.
+// Lines that are too short give an empty key (Uniq)
+func uniqKey(line string, fields uint, chars uint, width int) string {
+	i := 0
+	for ; fields > 0; fields-- {
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+			i++
+		}
+		for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+			i++
+		}
 	}
-	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
-	var lineText string
-	for scanner.Scan() {
-		if fields > 0 {
-			textField := strings.Fields(scanner.Text())
-			lineText = strings.Join(textField[fields:], " ")
-		} else if chars > 0 {
-			lineText = scanner.Text()[chars:]
-		} else {
-			lineText = scanner.Text()
+	for ; chars > 0 && i < len(line); chars-- {
+		_, size := utf8.DecodeRuneInString(line[i:])
+		i += size
+	}
+	if width < 0 {
+		return line[i:]
+	}
+
+	end := i
+	for ; width > 0 && end < len(line); width-- {
+		_, size := utf8.DecodeRuneInString(line[end:])
+		end += size
+	}
+	return line[i:end]
+}
+
+func Uniq(input string, output string, duplicated bool, unique bool, counter bool, fields uint, chars uint, ignoreCase bool, width int, zeroTerminated bool, allRepeated string, group string) error {
+	switch allRepeated {
+	case "", "none", "prepend", "separate":
+	default:
+		return fmt.Errorf("invalid argument '%s' for '--all-repeated'", allRepeated)
+	}
+	switch group {
+	case "", "separate", "prepend", "append", "both":
+	default:
+		return fmt.Errorf("invalid argument '%s' for '--group'", group)
+	}
+	if group != "" && (counter || duplicated || unique || allRepeated != "") {
+		return fmt.Errorf("--group is mutually exclusive with -c/-d/-D/-u")
+	}
+	if allRepeated != "" && counter {
+		return fmt.Errorf("Printing all duplicated lines and repeat counts is meaningless")
+	}
+
+	in, inName := os.Stdin, "standard input"
+	if input != "" && input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			return fmt.Errorf("Error opening file '%s': %w", input, err)
 		}
-		lineCounts[lineText]++
+		defer f.Close()
+		in, inName = f, input
 	}
 
-	if output != "" {
-		outputFileBool = true
+	out, outName := os.Stdout, "standard output"
+	if output != "" && output != "-" {
+		f, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("Error opening file '%s': %w", output, err)
+		}
+		defer f.Close()
+		out, outName = f, output
 	}
 
-	for line, count := range lineCounts {
-		if counter {
-			if outputFileBool {
-				textBytes = fmt.Appendf(textBytes, "%d %s\n", count, line)
-			} else {
-				fmt.Printf("%d %s\n", count, line)
-			}
-		} else if count > 1 && duplicated {
-			if outputFileBool {
-				textBytes = fmt.Appendln(textBytes, line)
-			} else {
-				fmt.Println(line)
-			}
-		} else if count == 1 && unique {
-			if outputFileBool {
-				textBytes = fmt.Appendln(textBytes, line)
-			} else {
-				fmt.Println(line)
+	eol := byte('\n')
+	if zeroTerminated {
+		eol = 0
+	}
+
+	reader := bufio.NewReader(in)
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	same := func(a, b string) bool {
+		if ignoreCase {
+			return strings.EqualFold(a, b)
+		}
+		return a == b
+	}
+	writeLine := func(line string) {
+		w.WriteString(line)
+		w.WriteByte(eol)
+	}
+
+	// Only the current group is kept: its first line, its key and its size.
+	// -D and --group write the other lines of a group as they are read
+	var first, firstKey string
+	count, groups := 0, 0
+	startGroup := func(line, key string) {
+		first, firstKey, count = line, key, 1
+		if group != "" {
+			// A separator already ends the previous group with append and both
+			if group == "prepend" || (group == "both" && groups == 0) || (group == "separate" && groups > 0) {
+				w.WriteByte(eol)
 			}
-		} else if !counter && !duplicated && !unique {
-			if outputFileBool {
-				textBytes = fmt.Appendln(textBytes, line)
-			} else {
-				fmt.Println(line)
+			writeLine(line)
+			groups++
+		}
+	}
+	addLine := func(line string) {
+		count++
+		if allRepeated != "" {
+			if count == 2 {
+				if allRepeated == "prepend" || (allRepeated == "separate" && groups > 0) {
+					w.WriteByte(eol)
+				}
+				writeLine(first)
+				groups++
 			}
+			writeLine(line)
+		} else if group != "" {
+			writeLine(line)
+		}
+	}
+	endGroup := func() {
+		switch {
+		case group == "append" || group == "both":
+			w.WriteByte(eol)
+		case group != "" || allRepeated != "":
+		case duplicated && count == 1, unique && count > 1:
+		case counter:
+			fmt.Fprintf(w, "%d %s%c", count, first, eol)
+		default:
+			writeLine(first)
 		}
 	}
 
-	if outputFileBool {
-		err := os.WriteFile(output, textBytes, 0666)
-		if err != nil {
-			return fmt.Errorf("Error writing on file '%s': %w", output, err)
+	for {
+		line, err := reader.ReadString(eol)
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("Error reading '%s': %w", inName, err)
+		}
+		if line == "" && err == io.EOF {
+			break
+		}
+		line = strings.TrimSuffix(line, string(eol))
+
+		key := uniqKey(line, fields, chars, width)
+		if count > 0 && same(key, firstKey) {
+			addLine(line)
+		} else {
+			if count > 0 {
+				endGroup()
+			}
+			startGroup(line, key)
+		}
+
+		if err == io.EOF {
+			break
 		}
 	}
+	if count > 0 {
+		endGroup()
+	}
 
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("Error writing on file '%s': %w", outName, err)
+	}
 	return nil
 }
 
-// Process list (2-;3-7;-3) in flags like -c (Cut)
+// Process list (2-;3-7;-3) in flags like -c. Items are separated by commas or
+// blanks, positions start at 1 and ranges cannot decrease (Cut)
 func cutList(list string) (nums [][2]int, err error) {
-	fields := strings.Split(list, ",")
-	for _, f := range fields {
-		f = strings.TrimSpace(f)
-		if f == "" {
-			continue
+	number := func(s string) (int, error) {
+		if s == "" || strings.Trim(s, "0123456789") != "" {
+			return 0, fmt.Errorf("Invalid byte, character or field list: '%s'", list)
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, fmt.Errorf("Byte offset, character or field number is too large: '%s'", s)
+		}
+		if n == 0 {
+			return 0, fmt.Errorf("Fields and positions are numbered from 1")
 		}
-		if strings.HasPrefix(f, "-") {
-			end, err := strconv.Atoi(strings.TrimPrefix(f, "-"))
+		return n, nil
+	}
+
+	items := strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	if len(items) == 0 {
+		return nil, fmt.Errorf("Invalid byte, character or field list: '%s'", list)
+	}
+
+	for _, item := range items {
+		lo, hi, isRange := strings.Cut(item, "-")
+		switch {
+		case !isRange:
+			n, err := number(item)
+			if err != nil {
+				return nil, err
+			}
+			nums = append(nums, [2]int{n - 1, n})
+		case lo == "" && hi == "":
+			return nil, fmt.Errorf("Invalid range with no endpoint: -")
+		case lo == "":
+			end, err := number(hi)
 			if err != nil {
-				return nil, fmt.Errorf("Invalid interval: %v", f)
+				return nil, err
 			}
 			nums = append(nums, [2]int{0, end})
-		} else if strings.HasSuffix(f, "-") {
-			start, err := strconv.Atoi(strings.TrimSuffix(f, "-"))
+		case hi == "":
+			start, err := number(lo)
 			if err != nil {
-				return nil, fmt.Errorf("Invalid interval: %v", f)
-
+				return nil, err
 			}
 			nums = append(nums, [2]int{start - 1, -1})
-		} else if strings.Contains(f, "-") {
-			parts := strings.SplitN(f, "-", 2)
-			start, err1 := strconv.Atoi(parts[0])
-			end, err2 := strconv.Atoi(parts[1])
-			if err1 != nil || err2 != nil {
-				return nil, fmt.Errorf("Invalid interval: %v", f)
+		default:
+			start, err := number(lo)
+			if err != nil {
+				return nil, err
 			}
-			nums = append(nums, [2]int{start - 1, end})
-		} else {
-			pos, err := strconv.Atoi(f)
+			end, err := number(hi)
 			if err != nil {
-				return nil, fmt.Errorf("Invalid number: %v", f)
+				return nil, err
+			}
+			if end < start {
+				return nil, fmt.Errorf("Invalid decreasing range: '%s'", item)
 			}
-			nums = append(nums, [2]int{pos - 1, pos})
+			nums = append(nums, [2]int{start - 1, end})
 		}
 	}
 	return nums, nil
 }
 
-func Cut(files []string, characters, fields, delimiter string, separatedOnly bool) error {
-	var listSlice [][2]int
-	var err error
-	var useChar, useField bool
+// Sort the ranges of a list and merge the ones that overlap or touch, so each
+// position is selected once and in order. An end of -1 is open (Cut)
+func cutNormalize(ranges [][2]int) [][2]int {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i][0] < ranges[j][0]
+	})
 
-	if characters != "" {
-		listSlice, err = cutList(characters)
-		if err != nil {
-			return err
+	var merged [][2]int
+	for _, r := range ranges {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if last[1] == -1 || r[0] <= last[1] {
+				if last[1] != -1 && (r[1] == -1 || r[1] > last[1]) {
+					last[1] = r[1]
+				}
+				continue
+			}
 		}
-		useChar = true
-	} else if fields != "" {
-		listSlice, err = cutList(fields)
-		if err != nil {
-			return err
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// This struct is used to save one byte, character or field of a line, with
+// the positions of the list it covers (Cut)
+type cutUnit struct {
+	text   string
+	lo, hi int
+}
+
+// Check if position pos is in the ranges of the list (Cut)
+func cutInRanges(ranges [][2]int, pos int) bool {
+	for _, r := range ranges {
+		if pos >= r[0] && (r[1] == -1 || pos < r[1]) {
+			return true
 		}
-		useField = true
 	}
+	return false
+}
+
+// Join the selected units of a line, or the others with --complement. A unit
+// is selected when all the positions it covers are. Adjacent units are joined
+// with join, separate runs with delim (Cut)
+func cutJoin(units []cutUnit, ranges [][2]int, complement bool, join, delim string) string {
+	var b strings.Builder
+	last := -1
+	for i, u := range units {
+		selected := true
+		for pos := u.lo; pos < u.hi; pos++ {
+			if cutInRanges(ranges, pos) == complement {
+				selected = false
+				break
+			}
+		}
+		if !selected {
+			continue
+		}
 
-	if delimiter == "" {
-		delimiter = "\t"
+		if last >= 0 && last == i-1 {
+			b.WriteString(join)
+		} else if last >= 0 {
+			b.WriteString(delim)
+		}
+		b.WriteString(u.text)
+		last = i
 	}
+	return b.String()
+}
 
-	for _, file := range files {
-		f, err := os.Open(file)
-		if err != nil {
-			return fmt.Errorf("Error opening file '%s': %w", file, err)
+// This struct is used to save the cut options (Cut)
+type cutOptions struct {
+	ranges                             [][2]int
+	useByte, useChar, useField         bool
+	separatedOnly, noSplit, complement bool
+	delimiter, outputDelimiter, eol    string
+}
+
+// Cut every line of one input. Lines are read whole whatever their length (Cut)
+func cutLines(r io.Reader, name string, w *bufio.Writer, opts cutOptions) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString(opts.eol[0])
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("Error reading '%s': %w", name, err)
 		}
-		defer f.Close()
+		if line == "" && err == io.EOF {
+			return nil
+		}
+		line = strings.TrimSuffix(line, opts.eol)
 
-		scanner := bufio.NewScanner(f)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if useChar {
-				for _, rng := range listSlice {
-					start := rng[0]
-					end := rng[1]
-					if start < 0 {
-						start = 0
-					}
-					if end == -1 || end > len(line) {
-						end = len(line)
-					}
-					if start >= len(line) {
-						continue
-					}
-					if end > len(line) {
-						end = len(line)
-					}
-					if start < end {
-						fmt.Print(line[start:end])
-					}
-				}
-				fmt.Println()
-			} else if useField {
-				if separatedOnly && !strings.Contains(line, delimiter) {
-					continue
-				}
-				fields := strings.Split(line, delimiter)
-				var output []string
-				for _, rng := range listSlice {
-					start := rng[0]
-					end := rng[1]
-					if start < 0 {
-						start = 0
-					}
-					if end == -1 || end > len(fields) {
-						end = len(fields)
-					}
-					if start >= len(fields) {
-						continue
-					}
-					if end > len(fields) {
-						end = len(fields)
-					}
-					if start < end {
-						output = append(output, fields[start:end]...)
-					}
+		var units []cutUnit
+		switch {
+		case opts.useByte && opts.noSplit:
+			// -n keeps multibyte characters whole. As POSIX extends a range down
+			// to the start of the character it begins in, a character is
+			// selected when its last byte is
+			for i := 0; i < len(line); {
+				_, size := utf8.DecodeRuneInString(line[i:])
+				units = append(units, cutUnit{line[i : i+size], i + size - 1, i + size})
+				i += size
+			}
+		case opts.useByte:
+			for i := 0; i < len(line); i++ {
+				units = append(units, cutUnit{line[i : i+1], i, i + 1})
+			}
+		case opts.useChar:
+			n := 0
+			for i := 0; i < len(line); n++ {
+				_, size := utf8.DecodeRuneInString(line[i:])
+				units = append(units, cutUnit{line[i : i+size], n, n + 1})
+				i += size
+			}
+		default:
+			// Lines without a delimiter are written whole unless -s is given
+			if !strings.Contains(line, opts.delimiter) {
+				if !opts.separatedOnly {
+					w.WriteString(line + opts.eol)
 				}
-				fmt.Println(strings.Join(output, delimiter))
-			} else {
-				fmt.Println(line)
+				break
+			}
+			for i, field := range strings.Split(line, opts.delimiter) {
+				units = append(units, cutUnit{field, i, i + 1})
+			}
+		}
+
+		if units != nil || !opts.useField {
+			join := ""
+			if opts.useField {
+				join = opts.outputDelimiter
 			}
+			w.WriteString(cutJoin(units, opts.ranges, opts.complement, join, opts.outputDelimiter) + opts.eol)
+		}
+
+		if err == io.EOF {
+			return nil
 		}
 	}
-	return nil
+}
+
+func Cut(files []string, bytesList, characters, fields, delimiter, outputDelimiter string, separatedOnly, noSplit, complement, zeroTerminated bool) error {
+	opts := cutOptions{
+		useByte:         bytesList != "",
+		useChar:         characters != "",
+		useField:        fields != "",
+		separatedOnly:   separatedOnly,
+		noSplit:         noSplit,
+		complement:      complement,
+		delimiter:       delimiter,
+		outputDelimiter: outputDelimiter,
+		eol:             "\n",
+	}
+
+	lists := 0
+	list := ""
+	for _, l := range []string{bytesList, characters, fields} {
+		if l != "" {
+			lists++
+			list = l
+		}
+	}
+	switch {
+	case lists == 0:
+		return fmt.Errorf("You must specify a list of bytes, characters, or fields")
+	case lists > 1:
+		return fmt.Errorf("Only one type of list may be specified")
+	case delimiter != "" && !opts.useField:
+		return fmt.Errorf("An input delimiter may be specified only when operating on fields")
+	case separatedOnly && !opts.useField:
+		return fmt.Errorf("Suppressing non-delimited lines makes sense only when operating on fields")
+	case utf8.RuneCountInString(delimiter) > 1:
+		return fmt.Errorf("The delimiter must be a single character")
+	}
+
+	ranges, err := cutList(list)
+	if err != nil {
+		return err
+	}
+	opts.ranges = cutNormalize(ranges)
+
+	if opts.delimiter == "" {
+		opts.delimiter = "\t"
+	}
+	if opts.useField && opts.outputDelimiter == "" {
+		opts.outputDelimiter = opts.delimiter
+	}
+	if zeroTerminated {
+		opts.eol = "\x00"
+	}
+
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	// An input that cannot be read is reported and the others are still cut
+	var errs []error
+	for _, file := range files {
+		if file == "-" {
+			if err := cutLines(os.Stdin, "standard input", w, opts); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Error opening file '%s': %w", file, err))
+			continue
+		}
+		if err := cutLines(f, file, w, opts); err != nil {
+			errs = append(errs, err)
+		}
+		f.Close()
+	}
+
+	if err := w.Flush(); err != nil {
+		errs = append(errs, fmt.Errorf("Error writing output: %w", err))
+	}
+	return errors.Join(errs...)
 }
 
 // Searches for a specific word/pharse (More)